@@ -1,12 +1,30 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/priyupadhyay/repo-sage/internal/analyzer"
+	"github.com/priyupadhyay/repo-sage/internal/clipboard"
 	"github.com/priyupadhyay/repo-sage/internal/config"
 	"github.com/priyupadhyay/repo-sage/internal/generator"
+	"github.com/priyupadhyay/repo-sage/internal/logging"
+	"github.com/priyupadhyay/repo-sage/internal/render"
+	"github.com/priyupadhyay/repo-sage/pkg/git"
+	"github.com/priyupadhyay/repo-sage/pkg/llm"
 	"github.com/spf13/cobra"
 )
 
@@ -16,43 +34,101 @@ var rootCmd = &cobra.Command{
 	Long: `repo-sage is a powerful CLI tool that analyzes Git repositories and generates 
 comprehensive documentation using AI. It helps you understand codebases by identifying
 key components, architecture, and generating clear documentation.`,
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		logLevel, _ := cmd.Flags().GetString("log-level")
+		noEmoji, _ := cmd.Flags().GetBool("no-emoji")
+		jsonLogs, _ := cmd.Flags().GetBool("json-logs")
+		noProgress, _ := cmd.Flags().GetBool("no-progress")
+		configPath, _ := cmd.Flags().GetString("config")
+		config.SetConfigPath(configPath)
+		if err := logging.Setup(logging.Options{
+			Level:      logLevel,
+			NoEmoji:    noEmoji,
+			JSON:       jsonLogs,
+			NoProgress: noProgress,
+		}); err != nil {
+			return err
+		}
+		return registerLanguages()
+	},
 }
 
 var analyzeCmd = &cobra.Command{
 	Use:   "analyze",
 	Short: "Analyze a Git repository",
 	Long: `Analyze a Git repository and generate comprehensive documentation.
-By default, performs a quick analysis of the repository structure and key files.
-Use --detailed for in-depth code analysis.
+By default (--mode standard), analyzes the repository structure and key files in
+one request. --mode quick asks only for a one-paragraph summary, in exactly one
+request; --mode detailed (or --detailed) analyzes all of the code in chunks.
 
 Example: repo-sage analyze --repo /path/to/repo --output docs/overview.md`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		repoPath, _ := cmd.Flags().GetString("repo")
-		outputPath, _ := cmd.Flags().GetString("output")
-		profileName, _ := cmd.Flags().GetString("profile")
+		if err := applyRepoDefaults(cmd, repoPath); err != nil {
+			return err
+		}
 		contextSize, _ := cmd.Flags().GetInt("context")
-		detailed, _ := cmd.Flags().GetBool("detailed")
+		detailed, summaryOnly, err := resolveMode(cmd)
+		if err != nil {
+			return err
+		}
+		maxContinuations, _ := cmd.Flags().GetInt("max-continuations")
+		contextFiles, _ := cmd.Flags().GetStringArray("context-file")
+		since, _ := cmd.Flags().GetString("since")
+		affectedSince, _ := cmd.Flags().GetString("affected-since")
+		rev, _ := cmd.Flags().GetString("rev")
+		subdir, _ := cmd.Flags().GetString("path")
+		resume, _ := cmd.Flags().GetBool("resume")
+		check, _ := cmd.Flags().GetBool("check")
+		saveResult, _ := cmd.Flags().GetString("save-result")
+		ignoreDirs, _ := cmd.Flags().GetStringArray("ignore-dir")
+		fileSource, _ := cmd.Flags().GetString("file-source")
+		untracked, _ := cmd.Flags().GetBool("include-untracked")
+		noGit, _ := cmd.Flags().GetBool("no-git")
+		includeSensitive, _ := cmd.Flags().GetStringArray("include-sensitive")
+		noRedact, _ := cmd.Flags().GetBool("no-redact")
+		allFiles, _ := cmd.Flags().GetBool("all-files")
+		depth, _ := cmd.Flags().GetInt("depth")
+		chunkSize, _ := cmd.Flags().GetInt("chunk-size")
+		chunkOverlap, _ := cmd.Flags().GetInt("chunk-overlap")
+		format, _ := cmd.Flags().GetString("format")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		promptSuffix, _ := cmd.Flags().GetString("prompt-suffix")
+		keepChunks, _ := cmd.Flags().GetString("keep-chunks")
+		chunkAppendix, _ := cmd.Flags().GetBool("chunk-appendix")
+		fileSummaries, _ := cmd.Flags().GetBool("file-summaries")
+		ciSummary, _ := cmd.Flags().GetBool("ci-summary")
+		dataModel, _ := cmd.Flags().GetBool("data-model")
+		sinceTag, _ := cmd.Flags().GetBool("since-tag")
+		groupLanguages, _ := cmd.Flags().GetBool("group-languages")
+		tokenBudget, _ := cmd.Flags().GetInt("token-budget")
 
-		// Load configuration
-		cfg, err := config.LoadConfig()
+		language, err := resolveLanguage(cmd)
 		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
+			return err
 		}
 
-		// Get profile
-		var profile config.Profile
-		if profileName != "" {
-			p, exists := cfg.GetProfile(profileName)
-			if !exists {
-				return fmt.Errorf("profile %q not found", profileName)
-			}
-			profile = p
-		} else {
-			p, _, err := cfg.GetDefaultProfile()
-			if err != nil {
-				return fmt.Errorf("no profile configured. Run 'repo-sage config add-profile' to get started")
-			}
-			profile = p
+		docFiles, err := resolveDocFiles(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Reject unknown formats before spending time on the analysis
+		switch format {
+		case "markdown", "html", "text", "json", "sarif":
+		default:
+			return fmt.Errorf("unknown format %q (expected markdown, html, text, json or sarif)", format)
+		}
+
+		profile, err := resolveProfile(cmd)
+		if err != nil {
+			return err
+		}
+
+		outputPath, err := resolveOutput(cmd, profile)
+		if err != nil {
+			return err
 		}
 
 		// Create analyzer
@@ -60,77 +136,468 @@ Example: repo-sage analyze --repo /path/to/repo --output docs/overview.md`,
 			OpenAIKey:   profile.APIKey,
 			APIBase:     profile.APIBase,
 			Model:       profile.Model,
+			ExtraBody:   profile.ExtraBody,
 			ContextSize: contextSize,
 			Detailed:    detailed,
+
+			MaxContinuations:  maxContinuations,
+			RequestsPerMinute: profile.RequestsPerMinute,
+			TokenBudget:       tokenBudget,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to create analyzer: %w", err)
 		}
 
+		if check {
+			if err := checkModel(a, profile.Model); err != nil {
+				return err
+			}
+		}
+
+		toClipboard, err := checkClipboard(cmd)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := runContext(cmd)
+		defer cancel()
+
 		// Analyze repository
-		result, err := a.Analyze(repoPath, analyzer.AnalyzeOptions{
-			OpenAIKey:   profile.APIKey,
-			APIBase:     profile.APIBase,
-			Model:       profile.Model,
-			ContextSize: contextSize,
-			Detailed:    detailed,
-			OutputPath:  outputPath,
+		result, err := a.Analyze(ctx, repoPath, analyzer.AnalyzeOptions{
+			OpenAIKey:    profile.APIKey,
+			APIBase:      profile.APIBase,
+			Model:        profile.Model,
+			ContextSize:  contextSize,
+			Detailed:     detailed,
+			SummaryOnly:  summaryOnly,
+			OutputPath:   outputPath,
+			ContextFiles: contextFiles,
+			DocFiles:     docFiles,
+			Since:        since,
+			Rev:          rev,
+			Subdir:       subdir,
+			Resume:       resume,
+			IgnoreDirs:   ignoreDirs,
+			FileSource:   fileSource,
+			Untracked:    untracked,
+			NoGit:        noGit,
+			NoRedact:     noRedact,
+			AllFiles:     allFiles,
+			DirDepth:     depth,
+			ChunkSize:    chunkSize,
+			ChunkOverlap: chunkOverlap,
+			Language:     language,
+			PromptSuffix: promptSuffix,
+			KeepChunks:   keepChunks,
+
+			ChunkAppendix:    chunkAppendix,
+			FileSummaries:    fileSummaries,
+			AffectedSince:    affectedSince,
+			CISummary:        ciSummary,
+			DataModel:        dataModel,
+			SinceTag:         sinceTag,
+			GroupLanguages:   groupLanguages,
+			MaxContinuations: maxContinuations,
+			IncludeSensitive: includeSensitive,
+			Concurrency:      concurrency,
 		})
 		if err != nil {
-			return fmt.Errorf("failed to analyze repository: %w", err)
+			return fmt.Errorf("failed to analyze repository: %w", timeoutError(ctx, cmd, err))
+		}
+
+		if saveResult != "" {
+			if err := analyzer.SaveResult(saveResult, result); err != nil {
+				return err
+			}
+			slog.Info(fmt.Sprintf("💾 Analysis result saved to %s", saveResult))
 		}
 
 		// Generate documentation
-		gen, err := generator.New()
+		if err := writeDocs(cmd, result, format, outputPath); err != nil {
+			return err
+		}
+
+		if toClipboard {
+			slog.Info("✨ Analysis complete! Documentation copied to the clipboard")
+			return nil
+		}
+		slog.Info(fmt.Sprintf("✨ Analysis complete! Documentation saved to %s", outputPath))
+		return nil
+	},
+}
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate documentation from a saved analysis result",
+	Long: `Render documentation from an analysis result saved with 'analyze --save-result',
+without calling the LLM again.
+
+Example: repo-sage generate --from result.json --format html --output docs/index.html`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		format, _ := cmd.Flags().GetString("format")
+
+		// generate makes no LLM calls, so only the global default_output applies
+		outputPath, err := resolveOutput(cmd, config.Profile{})
 		if err != nil {
-			return fmt.Errorf("failed to create generator: %w", err)
+			return err
 		}
 
-		doc, err := gen.Generate(result)
+		result, err := analyzer.LoadResult(from)
 		if err != nil {
-			return fmt.Errorf("failed to generate documentation: %w", err)
+			return err
 		}
 
-		// Write output
-		if err := os.WriteFile(outputPath, []byte(doc), 0644); err != nil {
-			return fmt.Errorf("failed to write output: %w", err)
+		if err := writeDocs(cmd, result, format, outputPath); err != nil {
+			return err
 		}
 
-		fmt.Printf("✨ Analysis complete! Documentation saved to %s\n", outputPath)
+		if toClipboard, _ := cmd.Flags().GetBool("clipboard"); toClipboard {
+			slog.Info("✨ Documentation copied to the clipboard")
+			return nil
+		}
+		slog.Info(fmt.Sprintf("✨ Documentation saved to %s", outputPath))
 		return nil
 	},
 }
 
+var compareCmd = &cobra.Command{
+	Use:   "compare OLD NEW",
+	Short: "Compare two saved analysis results",
+	Long: `Show how a repository changed between two analysis results saved with
+'analyze --save-result', such as those of two releases: added, removed and moved
+components, shifts in language statistics, dependency changes, entry points and
+the license. No LLM calls are made.
+
+Example: repo-sage compare v1.0.json v2.0.json`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		renderName, _ := cmd.Flags().GetString("render")
+		renderMode, err := render.ParseMode(renderName)
+		if err != nil {
+			return err
+		}
+
+		before, err := analyzer.LoadResult(args[0])
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		after, err := analyzer.LoadResult(args[1])
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[1], err)
+		}
+
+		render.Write(formatDiff(before, after, analyzer.CompareResults(before, after)), renderMode)
+		return nil
+	},
+}
+
+// formatDiff renders the differences between two analyses as Markdown
+func formatDiff(before, after *analyzer.AnalysisResult, diff *analyzer.ResultDiff) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Changes in %s\n\n", after.RepoInfo.Name)
+	fmt.Fprintf(&b, "From %s to %s.\n", resultLabel(before), resultLabel(after))
+	if diff.Empty() {
+		b.WriteString("\nNo differences in components, languages, dependencies, entry points or license.\n")
+		return b.String()
+	}
+
+	if len(diff.AddedComponents)+len(diff.RemovedComponents)+len(diff.MovedComponents) > 0 {
+		b.WriteString("\n## Components\n\n")
+		for _, c := range diff.AddedComponents {
+			fmt.Fprintf(&b, "- Added **%s** (%s) at `%s`\n", c.Name, c.Type, c.Path)
+		}
+		for _, c := range diff.RemovedComponents {
+			fmt.Fprintf(&b, "- Removed **%s** (%s) at `%s`\n", c.Name, c.Type, c.Path)
+		}
+		for _, m := range diff.MovedComponents {
+			fmt.Fprintf(&b, "- Changed **%s**: %s at `%s` -> %s at `%s`\n", m.New.Name, m.Old.Type, m.Old.Path, m.New.Type, m.New.Path)
+		}
+	}
+
+	if len(diff.Languages) > 0 {
+		b.WriteString("\n## Languages\n\n")
+		for _, l := range diff.Languages {
+			switch {
+			case l.Old == 0:
+				fmt.Fprintf(&b, "- Added %s: %.1f%%\n", l.Language, l.New)
+			case l.New == 0:
+				fmt.Fprintf(&b, "- Removed %s (was %.1f%%)\n", l.Language, l.Old)
+			default:
+				fmt.Fprintf(&b, "- %s: %.1f%% -> %.1f%% (%+.1f points)\n", l.Language, l.Old, l.New, l.New-l.Old)
+			}
+		}
+	}
+
+	if len(diff.Dependencies) > 0 {
+		b.WriteString("\n## Dependencies\n\n")
+		for _, d := range diff.Dependencies {
+			switch {
+			case d.Old == "":
+				fmt.Fprintf(&b, "- Added %s %s\n", d.Name, d.New)
+			case d.New == "":
+				fmt.Fprintf(&b, "- Removed %s %s\n", d.Name, d.Old)
+			default:
+				fmt.Fprintf(&b, "- %s: %s -> %s\n", d.Name, d.Old, d.New)
+			}
+		}
+	}
+
+	if len(diff.AddedEntryPoints)+len(diff.RemovedEntryPoints) > 0 {
+		b.WriteString("\n## Entry Points\n\n")
+		for _, e := range diff.AddedEntryPoints {
+			fmt.Fprintf(&b, "- Added `%s`\n", e)
+		}
+		for _, e := range diff.RemovedEntryPoints {
+			fmt.Fprintf(&b, "- Removed `%s`\n", e)
+		}
+	}
+
+	if diff.OldLicense != diff.NewLicense {
+		fmt.Fprintf(&b, "\n## License\n\n- %s -> %s\n", orNone(diff.OldLicense), orNone(diff.NewLicense))
+	}
+	return b.String()
+}
+
+// resultLabel identifies an analysis by its revision, or its time when it has none
+func resultLabel(result *analyzer.AnalysisResult) string {
+	switch {
+	case result.Revision != "":
+		return "revision " + result.Revision
+	case !result.AnalyzedAt.IsZero():
+		return "the analysis of " + result.AnalyzedAt.Format("2006-01-02 15:04")
+	}
+	return "an undated analysis"
+}
+
+// orNone returns the value, or "none" when it is empty
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
 var explainCmd = &cobra.Command{
 	Use:   "explain",
 	Short: "Explain a specific file",
 	Long: `Generate a detailed explanation of a specific file in the repository.
-Example: repo-sage explain --file path/to/file.go`,
+The explanation is rendered with terminal styling when stdout is a TTY; use
+--render plain for unformatted output. Use --symbol or --line to explain a
+single function or type instead of the whole file.
+
+Example: repo-sage explain --file path/to/file.go --symbol Repository.ListFiles`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		filePath, _ := cmd.Flags().GetString("file")
-		profileName, _ := cmd.Flags().GetString("profile")
 		contextSize, _ := cmd.Flags().GetInt("context")
+		maxContinuations, _ := cmd.Flags().GetInt("max-continuations")
+		renderName, _ := cmd.Flags().GetString("render")
+		symbol, _ := cmd.Flags().GetString("symbol")
+		line, _ := cmd.Flags().GetInt("line")
+		noRedact, _ := cmd.Flags().GetBool("no-redact")
+		noCache, _ := cmd.Flags().GetBool("no-cache")
+		promptSuffix, _ := cmd.Flags().GetString("prompt-suffix")
+		stream, _ := cmd.Flags().GetBool("stream")
 
-		// Load configuration
-		cfg, err := config.LoadConfig()
+		renderMode, err := render.ParseMode(renderName)
 		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
+			return err
 		}
 
-		// Get profile
-		var profile config.Profile
-		if profileName != "" {
-			p, exists := cfg.GetProfile(profileName)
-			if !exists {
-				return fmt.Errorf("profile %q not found", profileName)
+		language, err := resolveLanguage(cmd)
+		if err != nil {
+			return err
+		}
+
+		profile, err := resolveProfile(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Create analyzer
+		a, err := analyzer.NewAnalyzer(analyzer.AnalyzeOptions{
+			OpenAIKey:   profile.APIKey,
+			APIBase:     profile.APIBase,
+			Model:       profile.Model,
+			ExtraBody:   profile.ExtraBody,
+			ContextSize: contextSize,
+
+			MaxContinuations:  maxContinuations,
+			RequestsPerMinute: profile.RequestsPerMinute,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create analyzer: %w", err)
+		}
+
+		// Streamed output is printed as it arrives, so it cannot be styled
+		var streamed func(text string)
+		printed := false
+		if stream {
+			streamed = func(text string) {
+				fmt.Print(text)
+				printed = true
 			}
-			profile = p
-		} else {
-			p, _, err := cfg.GetDefaultProfile()
-			if err != nil {
-				return fmt.Errorf("no profile configured. Run 'repo-sage config add-profile' to get started")
+		}
+
+		if _, err := checkClipboard(cmd); err != nil {
+			return err
+		}
+
+		ctx, cancel := runContext(cmd)
+		defer cancel()
+
+		// Explain file
+		explanation, err := a.ExplainFile(ctx, filePath, analyzer.ExplainOptions{
+			ContextSize: contextSize,
+			OpenAIKey:   profile.APIKey,
+			APIBase:     profile.APIBase,
+			Model:       profile.Model,
+
+			MaxContinuations: maxContinuations,
+
+			Symbol:   symbol,
+			Line:     line,
+			NoRedact: noRedact,
+			Language: language,
+			NoCache:  noCache,
+
+			PromptSuffix: promptSuffix,
+			Stream:       streamed,
+		})
+		if printed {
+			fmt.Println()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to explain file: %w", timeoutError(ctx, cmd, err))
+		}
+
+		return writeExplanation(cmd, explanation, stream, renderMode)
+	},
+}
+
+var explainDiffCmd = &cobra.Command{
+	Use:   "explain-diff",
+	Short: "Explain how a file changed since a revision",
+	Long: `Explain what changed in a file since a base commit, tag or branch, why the
+change was likely made and why it matters, for code review. Both versions of
+the file are sent to the model. The new version is the working tree unless
+--head names another revision.
+
+Example: repo-sage explain-diff --file internal/analyzer/impl.go --base main`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filePath, _ := cmd.Flags().GetString("file")
+		base, _ := cmd.Flags().GetString("base")
+		head, _ := cmd.Flags().GetString("head")
+		contextSize, _ := cmd.Flags().GetInt("context")
+		maxContinuations, _ := cmd.Flags().GetInt("max-continuations")
+		renderName, _ := cmd.Flags().GetString("render")
+		noRedact, _ := cmd.Flags().GetBool("no-redact")
+		promptSuffix, _ := cmd.Flags().GetString("prompt-suffix")
+		stream, _ := cmd.Flags().GetBool("stream")
+
+		renderMode, err := render.ParseMode(renderName)
+		if err != nil {
+			return err
+		}
+
+		language, err := resolveLanguage(cmd)
+		if err != nil {
+			return err
+		}
+
+		profile, err := resolveProfile(cmd)
+		if err != nil {
+			return err
+		}
+
+		a, err := analyzer.NewAnalyzer(analyzer.AnalyzeOptions{
+			OpenAIKey:   profile.APIKey,
+			APIBase:     profile.APIBase,
+			Model:       profile.Model,
+			ExtraBody:   profile.ExtraBody,
+			ContextSize: contextSize,
+
+			MaxContinuations:  maxContinuations,
+			RequestsPerMinute: profile.RequestsPerMinute,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create analyzer: %w", err)
+		}
+
+		// Streamed output is printed as it arrives, so it cannot be styled
+		var streamed func(text string)
+		printed := false
+		if stream {
+			streamed = func(text string) {
+				fmt.Print(text)
+				printed = true
 			}
-			profile = p
+		}
+
+		if _, err := checkClipboard(cmd); err != nil {
+			return err
+		}
+
+		ctx, cancel := runContext(cmd)
+		defer cancel()
+
+		explanation, err := a.ExplainDiff(ctx, filePath, analyzer.ExplainDiffOptions{
+			ContextSize: contextSize,
+			Base:        base,
+			Head:        head,
+
+			NoRedact:     noRedact,
+			Language:     language,
+			PromptSuffix: promptSuffix,
+			Stream:       streamed,
+		})
+		if printed {
+			fmt.Println()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to explain the diff: %w", timeoutError(ctx, cmd, err))
+		}
+
+		return writeExplanation(cmd, explanation, stream, renderMode)
+	},
+}
+
+var componentsCmd = &cobra.Command{
+	Use:   "components",
+	Short: "List the main components of a repository",
+	Long: `Identify the main components of a Git repository and print them as a table or JSON,
+without generating the full documentation.
+
+With --format jsonl, each component is printed as a JSON object on its own line
+as soon as the model returns it.
+
+Example: repo-sage components --repo /path/to/repo --format json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repoPath, _ := cmd.Flags().GetString("repo")
+		contextSize, _ := cmd.Flags().GetInt("context")
+		maxContinuations, _ := cmd.Flags().GetInt("max-continuations")
+		format, _ := cmd.Flags().GetString("format")
+		contextFiles, _ := cmd.Flags().GetStringArray("context-file")
+		ignoreDirs, _ := cmd.Flags().GetStringArray("ignore-dir")
+		fileSource, _ := cmd.Flags().GetString("file-source")
+		untracked, _ := cmd.Flags().GetBool("include-untracked")
+		noGit, _ := cmd.Flags().GetBool("no-git")
+		includeSensitive, _ := cmd.Flags().GetStringArray("include-sensitive")
+		noRedact, _ := cmd.Flags().GetBool("no-redact")
+		depth, _ := cmd.Flags().GetInt("depth")
+
+		if format != "table" && format != "json" && format != "jsonl" {
+			return fmt.Errorf("unknown format %q (expected table, json or jsonl)", format)
+		}
+
+		docFiles, err := resolveDocFiles(cmd)
+		if err != nil {
+			return err
+		}
+
+		profile, err := resolveProfile(cmd)
+		if err != nil {
+			return err
 		}
 
 		// Create analyzer
@@ -138,24 +605,413 @@ Example: repo-sage explain --file path/to/file.go`,
 			OpenAIKey:   profile.APIKey,
 			APIBase:     profile.APIBase,
 			Model:       profile.Model,
+			ExtraBody:   profile.ExtraBody,
 			ContextSize: contextSize,
+
+			MaxContinuations:  maxContinuations,
+			RequestsPerMinute: profile.RequestsPerMinute,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to create analyzer: %w", err)
 		}
 
-		// Explain file
-		explanation, err := a.ExplainFile(filePath, analyzer.ExplainOptions{
+		options := analyzer.AnalyzeOptions{
+			ContextSize:  contextSize,
+			ContextFiles: contextFiles,
+			DocFiles:     docFiles,
+			IgnoreDirs:   ignoreDirs,
+			FileSource:   fileSource,
+			Untracked:    untracked,
+			NoGit:        noGit,
+			NoRedact:     noRedact,
+			DirDepth:     depth,
+
+			IncludeSensitive: includeSensitive,
+		}
+		// JSON lines are written as each component is identified, for live display
+		var streamErr error
+		if format == "jsonl" {
+			enc := json.NewEncoder(os.Stdout)
+			options.StreamComponents = func(c analyzer.Component) {
+				if err := enc.Encode(c); err != nil && streamErr == nil {
+					streamErr = err
+				}
+			}
+		}
+
+		components, err := a.Components(repoPath, options)
+		if err != nil {
+			return fmt.Errorf("failed to identify components: %w", err)
+		}
+		if format == "jsonl" {
+			return streamErr
+		}
+
+		if format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(components)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTYPE\tPATH\tDESCRIPTION")
+		for _, c := range components {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Name, c.Type, c.Path, c.Description)
+		}
+		return w.Flush()
+	},
+}
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog",
+	Short: "Generate release notes from recent commits",
+	Long: `Summarize recent commits into a changelog grouped by features, fixes and chores.
+Reads the last N commits, or a revision range, from the repository history.
+
+Example: repo-sage changelog --repo . --range v1.0.0..HEAD --output CHANGELOG.md`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repoPath, _ := cmd.Flags().GetString("repo")
+		outputPath, _ := cmd.Flags().GetString("output")
+		contextSize, _ := cmd.Flags().GetInt("context")
+		maxContinuations, _ := cmd.Flags().GetInt("max-continuations")
+		count, _ := cmd.Flags().GetInt("count")
+		revRange, _ := cmd.Flags().GetString("range")
+		withDiffs, _ := cmd.Flags().GetBool("diffs")
+
+		profile, err := resolveProfile(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Create analyzer
+		a, err := analyzer.NewAnalyzer(analyzer.AnalyzeOptions{
+			OpenAIKey:   profile.APIKey,
+			APIBase:     profile.APIBase,
+			Model:       profile.Model,
+			ExtraBody:   profile.ExtraBody,
+			ContextSize: contextSize,
+
+			MaxContinuations:  maxContinuations,
+			RequestsPerMinute: profile.RequestsPerMinute,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create analyzer: %w", err)
+		}
+
+		// A range selects its own commits, so only apply the default count without one
+		if revRange != "" && !cmd.Flags().Changed("count") {
+			count = 0
+		}
+
+		changelog, err := a.Changelog(repoPath, analyzer.ChangelogOptions{
 			ContextSize: contextSize,
+			Range:       revRange,
+			Count:       count,
+			WithDiffs:   withDiffs,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to generate changelog: %w", err)
+		}
+
+		if outputPath == "" {
+			fmt.Println(changelog)
+			return nil
+		}
+
+		if err := os.WriteFile(outputPath, []byte(changelog), 0644); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+
+		slog.Info(fmt.Sprintf("✨ Changelog saved to %s", outputPath))
+		return nil
+	},
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Report what an analysis would process, without calling the model",
+	Long: `Run only the local passes of an analysis and report the file count, total size,
+language breakdown and the estimated requests and tokens of the quick, standard
+and detailed modes, to help choose between them. No requests are sent to the model.
+
+Example: repo-sage stats --repo /path/to/repo --cost-per-mtok 2.50`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repoPath, _ := cmd.Flags().GetString("repo")
+		contextSize, _ := cmd.Flags().GetInt("context")
+		contextFiles, _ := cmd.Flags().GetStringArray("context-file")
+		since, _ := cmd.Flags().GetString("since")
+		affectedSince, _ := cmd.Flags().GetString("affected-since")
+		rev, _ := cmd.Flags().GetString("rev")
+		subdir, _ := cmd.Flags().GetString("path")
+		ignoreDirs, _ := cmd.Flags().GetStringArray("ignore-dir")
+		fileSource, _ := cmd.Flags().GetString("file-source")
+		untracked, _ := cmd.Flags().GetBool("include-untracked")
+		noGit, _ := cmd.Flags().GetBool("no-git")
+		includeSensitive, _ := cmd.Flags().GetStringArray("include-sensitive")
+		allFiles, _ := cmd.Flags().GetBool("all-files")
+		depth, _ := cmd.Flags().GetInt("depth")
+		chunkSize, _ := cmd.Flags().GetInt("chunk-size")
+		chunkOverlap, _ := cmd.Flags().GetInt("chunk-overlap")
+		costPerMTok, _ := cmd.Flags().GetFloat64("cost-per-mtok")
+		format, _ := cmd.Flags().GetString("format")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		fileSummaries, _ := cmd.Flags().GetBool("file-summaries")
+		groupLanguages, _ := cmd.Flags().GetBool("group-languages")
+
+		if format != "table" && format != "json" {
+			return fmt.Errorf("unknown format %q (expected table or json)", format)
+		}
+
+		docFiles, err := resolveDocFiles(cmd)
+		if err != nil {
+			return err
+		}
+
+		stats, err := analyzer.Stats(repoPath, analyzer.AnalyzeOptions{
+			ContextSize:  contextSize,
+			ContextFiles: contextFiles,
+			DocFiles:     docFiles,
+			Since:        since,
+			Rev:          rev,
+			Subdir:       subdir,
+			IgnoreDirs:   ignoreDirs,
+			FileSource:   fileSource,
+			Untracked:    untracked,
+			NoGit:        noGit,
+			AllFiles:     allFiles,
+			DirDepth:     depth,
+			ChunkSize:    chunkSize,
+			ChunkOverlap: chunkOverlap,
+
+			FileSummaries:    fileSummaries,
+			AffectedSince:    affectedSince,
+			GroupLanguages:   groupLanguages,
+			IncludeSensitive: includeSensitive,
+			Concurrency:      concurrency,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to collect repository stats: %w", err)
+		}
+
+		if format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(stats)
+		}
+
+		fmt.Printf("Files:      %d (%d read by detailed analysis)\n", stats.Files, stats.SourceFiles)
+		fmt.Printf("Total size: %s\n", formatBytes(stats.TotalBytes))
+		fmt.Printf("Languages:  %s\n\n", formatLanguageShares(stats.Languages))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		header := "MODE\tREQUESTS\tCHUNKS\tINPUT TOKENS"
+		if costPerMTok > 0 {
+			header += "\tCOST"
+		}
+		fmt.Fprintln(w, header)
+		for _, mode := range []struct {
+			name     string
+			estimate llm.RequestEstimate
+		}{{"quick", stats.Quick}, {"standard", stats.Standard}, {"detailed", stats.Detailed}} {
+			row := fmt.Sprintf("%s\t%d\t%d\t~%d", mode.name, mode.estimate.Requests, mode.estimate.Chunks, mode.estimate.InputTokens)
+			if costPerMTok > 0 {
+				row += fmt.Sprintf("\t~$%.2f", float64(mode.estimate.InputTokens)/1e6*costPerMTok)
+			}
+			fmt.Fprintln(w, row)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Println("\nToken counts are approximate and exclude responses and continuations.")
+		return nil
+	},
+}
+
+var chatCmd = &cobra.Command{
+	Use:   "chat",
+	Short: "Ask questions about a repository interactively",
+	Long: `Read a repository's files, then answer questions about it one at a time.
+Each question is sent with the files that best match it and the most recent
+questions and answers, so follow-up questions can refer to earlier ones.
+Type exit or press Ctrl-D to quit.
+
+Example: repo-sage chat --repo /path/to/repo`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repoPath, _ := cmd.Flags().GetString("repo")
+		contextSize, _ := cmd.Flags().GetInt("context")
+		maxContinuations, _ := cmd.Flags().GetInt("max-continuations")
+		renderName, _ := cmd.Flags().GetString("render")
+		ignoreDirs, _ := cmd.Flags().GetStringArray("ignore-dir")
+		fileSource, _ := cmd.Flags().GetString("file-source")
+		untracked, _ := cmd.Flags().GetBool("include-untracked")
+		noGit, _ := cmd.Flags().GetBool("no-git")
+		includeSensitive, _ := cmd.Flags().GetStringArray("include-sensitive")
+		noRedact, _ := cmd.Flags().GetBool("no-redact")
+		promptSuffix, _ := cmd.Flags().GetString("prompt-suffix")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+
+		renderMode, err := render.ParseMode(renderName)
+		if err != nil {
+			return err
+		}
+
+		language, err := resolveLanguage(cmd)
+		if err != nil {
+			return err
+		}
+
+		profile, err := resolveProfile(cmd)
+		if err != nil {
+			return err
+		}
+
+		a, err := analyzer.NewAnalyzer(analyzer.AnalyzeOptions{
 			OpenAIKey:   profile.APIKey,
 			APIBase:     profile.APIBase,
 			Model:       profile.Model,
+			ExtraBody:   profile.ExtraBody,
+			ContextSize: contextSize,
+
+			MaxContinuations:  maxContinuations,
+			RequestsPerMinute: profile.RequestsPerMinute,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create analyzer: %w", err)
+		}
+
+		session, err := a.Chat(repoPath, analyzer.ChatOptions{
+			ContextSize:  contextSize,
+			IgnoreDirs:   ignoreDirs,
+			FileSource:   fileSource,
+			Untracked:    untracked,
+			NoGit:        noGit,
+			NoRedact:     noRedact,
+			Language:     language,
+			PromptSuffix: promptSuffix,
+
+			IncludeSensitive: includeSensitive,
+			Concurrency:      concurrency,
 		})
 		if err != nil {
-			return fmt.Errorf("failed to explain file: %w", err)
+			return err
+		}
+
+		fmt.Printf("Ask a question about %s (type exit or press Ctrl-D to quit).\n", session.Name)
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for {
+			fmt.Print("> ")
+			if !scanner.Scan() {
+				fmt.Println()
+				break
+			}
+			question := strings.TrimSpace(scanner.Text())
+			if question == "" {
+				continue
+			}
+			if question == "exit" || question == "quit" {
+				break
+			}
+
+			// A failed question should not end the conversation
+			answer, err := session.Ask(question)
+			if err != nil {
+				logError(err)
+				continue
+			}
+			render.Write(answer.Answer, renderMode)
+			if len(answer.Sources) > 0 {
+				fmt.Printf("\nSources: %s\n", strings.Join(answer.Sources, ", "))
+			}
+			fmt.Println()
+		}
+		return scanner.Err()
+	},
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that repo-sage is set up correctly",
+	Long: `Check the configuration, the selected profile, the LLM endpoint and git, and
+print a pass/fail report. Exits with an error when any check fails.
+
+Example: repo-sage doctor --profile work`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var checks []doctorCheck
+		report := func(name string, err error, detail string) bool {
+			checks = append(checks, doctorCheck{Name: name, Err: err, Detail: detail})
+			return err == nil
+		}
+
+		// Configuration, which is optional when flags or the environment give an API key
+		profileName, _ := cmd.Flags().GetString("profile")
+		ephemeral := profileName == "" && flagOrEnv(cmd, "api-key", envAPIKey) != ""
+		configPath, err := config.GetConfigPath()
+		cfg := &config.Config{}
+		if err == nil {
+			if _, statErr := os.Stat(configPath); statErr != nil {
+				if ephemeral {
+					report("Config file", nil, "none; using settings from flags or the environment")
+				} else {
+					report("Config file", fmt.Errorf("not found at %s; run 'repo-sage config add-profile'", configPath), "")
+				}
+			} else if cfg, err = config.LoadConfig(); err != nil {
+				report("Config file", err, "")
+				cfg = &config.Config{}
+			} else {
+				report("Config file", nil, configPath)
+			}
+		} else {
+			report("Config file", err, "")
+		}
+		if _, name, err := cfg.GetDefaultProfile(); err != nil && !ephemeral {
+			report("Default profile", fmt.Errorf("%w; run 'repo-sage config use-profile'", err), "")
+		} else if err == nil {
+			report("Default profile", nil, name)
+		}
+
+		// Endpoint, skipping checks that depend on an earlier failure
+		source := profileName
+		switch {
+		case ephemeral:
+			source = "flags or environment"
+		case source == "":
+			source = cfg.DefaultProfile
+		}
+		profile, err := resolveProfile(cmd)
+		if report("Profile", err, source) {
+			apiBase := profile.APIBase
+			if apiBase == "" {
+				apiBase = llm.DefaultAPIBase
+			}
+			reachable := report("API base reachable", checkReachable(apiBase), apiBase)
+			validKey := report("API key format", checkKeyFormat(apiBase, profile.APIKey), maskAPIKey(profile.APIKey))
+			if reachable && validKey {
+				report("Model accessible", checkModelAccess(profile), profile.Model)
+			}
+		}
+
+		// Tools
+		version, err := gitVersion()
+		report("Git available", err, version)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		failed := 0
+		for _, c := range checks {
+			status, detail := "PASS", c.Detail
+			if c.Err != nil {
+				status, detail = "FAIL", c.Err.Error()
+				failed++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", status, c.Name, detail)
 		}
+		w.Flush()
 
-		fmt.Println(explanation)
+		if failed > 0 {
+			return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+		}
 		return nil
 	},
 }
@@ -177,6 +1033,11 @@ var addProfileCmd = &cobra.Command{
 		apiBase, _ := cmd.Flags().GetString("api-base")
 		apiKey, _ := cmd.Flags().GetString("api-key")
 		model, _ := cmd.Flags().GetString("model")
+		defaultOutput, _ := cmd.Flags().GetString("default-output")
+		requestsPerMinute, _ := cmd.Flags().GetInt("requests-per-minute")
+		if requestsPerMinute < 0 {
+			return fmt.Errorf("--requests-per-minute must not be negative")
+		}
 
 		cfg, err := config.LoadConfig()
 		if err != nil {
@@ -184,9 +1045,12 @@ var addProfileCmd = &cobra.Command{
 		}
 
 		profile := config.Profile{
-			APIBase: apiBase,
-			APIKey:  apiKey,
-			Model:   model,
+			APIBase:       apiBase,
+			APIKey:        apiKey,
+			Model:         model,
+			DefaultOutput: defaultOutput,
+
+			RequestsPerMinute: requestsPerMinute,
 		}
 
 		cfg.AddProfile(name, profile)
@@ -200,6 +1064,40 @@ var addProfileCmd = &cobra.Command{
 	},
 }
 
+var copyProfileCmd = &cobra.Command{
+	Use:   "copy-profile [src] [dst]",
+	Short: "Copy a profile under a new name",
+	Long: `Copy a profile's endpoint, API key and other settings to a new profile, for
+example to try a different model against the same endpoint.
+
+Example: repo-sage config copy-profile prod prod-mini --model gpt-4o-mini`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src, dst := args[0], args[1]
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		profile, err := cfg.CopyProfile(src, dst)
+		if err != nil {
+			return err
+		}
+		if model, _ := cmd.Flags().GetString("model"); model != "" {
+			profile.Model = model
+			cfg.AddProfile(dst, profile)
+		}
+
+		if err := config.SaveConfig(cfg); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+
+		fmt.Printf("Profile %q copied to %q\n", src, dst)
+		return nil
+	},
+}
+
 var listProfilesCmd = &cobra.Command{
 	Use:   "list-profiles",
 	Short: "List all configured profiles",
@@ -226,6 +1124,12 @@ var listProfilesCmd = &cobra.Command{
 			fmt.Printf("  API Base: %s\n", profile.APIBase)
 			fmt.Printf("  Model: %s\n", profile.Model)
 			fmt.Printf("  API Key: %s\n", maskAPIKey(profile.APIKey))
+			if profile.DefaultOutput != "" {
+				fmt.Printf("  Default Output: %s\n", profile.DefaultOutput)
+			}
+			if profile.RequestsPerMinute > 0 {
+				fmt.Printf("  Requests Per Minute: %d\n", profile.RequestsPerMinute)
+			}
 			fmt.Println()
 		}
 
@@ -233,6 +1137,41 @@ var listProfilesCmd = &cobra.Command{
 	},
 }
 
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "List models available from a profile's endpoint",
+	Long:  `Query the LLM endpoint of a profile and list the models it serves.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profile, err := resolveProfile(cmd)
+		if err != nil {
+			return err
+		}
+
+		a, err := analyzer.NewAnalyzer(analyzer.AnalyzeOptions{
+			OpenAIKey: profile.APIKey,
+			APIBase:   profile.APIBase,
+			Model:     profile.Model,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create analyzer: %w", err)
+		}
+
+		models, err := a.ListModels()
+		if err != nil {
+			return err
+		}
+
+		for _, model := range models {
+			mark := " "
+			if model == profile.Model {
+				mark = "*"
+			}
+			fmt.Printf("%s %s\n", mark, model)
+		}
+		return nil
+	},
+}
+
 var useProfileCmd = &cobra.Command{
 	Use:   "use-profile [name]",
 	Short: "Set the default profile",
@@ -259,7 +1198,614 @@ var useProfileCmd = &cobra.Command{
 	},
 }
 
+// writeDocs renders the analysis result in the given format and writes it to
+// outputPath, splitting Markdown across files when outputPath is a directory
+func writeDocs(cmd *cobra.Command, result *analyzer.AnalysisResult, format, outputPath string) error {
+	noEmoji, _ := cmd.Flags().GetBool("no-emoji")
+	componentTypes, _ := cmd.Flags().GetStringArray("component-type")
+	showStructure, _ := cmd.Flags().GetBool("show-structure")
+	force, _ := cmd.Flags().GetBool("force")
+	lineEndings, _ := cmd.Flags().GetString("line-endings")
+	bom, _ := cmd.Flags().GetBool("bom")
+	frontmatter, _ := cmd.Flags().GetBool("frontmatter")
+	frontmatterTags, _ := cmd.Flags().GetStringArray("frontmatter-tag")
+	templateDir, _ := cmd.Flags().GetString("output-template-dir")
+	toClipboard, _ := cmd.Flags().GetBool("clipboard")
+	footer, noFooter, err := resolveFooter(cmd)
+	if err != nil {
+		return err
+	}
+	if lineEndings != "lf" && lineEndings != "crlf" {
+		return fmt.Errorf("unknown line endings %q (expected lf or crlf)", lineEndings)
+	}
+	if templateDir != "" && toClipboard {
+		return fmt.Errorf("--output-template-dir writes several files, so it cannot be used with --clipboard")
+	}
+	if templateDir != "" && (format != "markdown" || !isDirOutput(outputPath)) {
+		return fmt.Errorf("--output-template-dir needs --format markdown and a directory --output, such as docs/")
+	}
+	// JSON and SARIF are read by tools, which need no BOM or CRLF, and plain
+	// text has no markers to splice between
+	text := format == "markdown" || format == "html" || format == "text"
+	options := writeOptions{
+		splice: format == "markdown" || format == "html",
+		force:  force,
+		crlf:   text && lineEndings == "crlf",
+		bom:    text && bom,
+	}
+	gen, err := generator.New(generator.Options{
+		NoEmoji:        noEmoji,
+		ComponentTypes: componentTypes,
+		ShowStructure:  showStructure,
+
+		Frontmatter:     frontmatter || len(frontmatterTags) > 0,
+		FrontmatterTags: frontmatterTags,
+
+		Footer:   footer,
+		NoFooter: noFooter,
+
+		TemplateDir: templateDir,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create generator: %w", err)
+	}
+
+	var doc string
+	switch format {
+	case "markdown":
+		// Split documentation across files when the output is a directory;
+		// the clipboard holds a single document, so --output is ignored
+		if isDirOutput(outputPath) && !toClipboard {
+			files, err := gen.GenerateFiles(result)
+			if err != nil {
+				return fmt.Errorf("failed to generate documentation: %w", err)
+			}
+
+			if err := os.MkdirAll(outputPath, 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			for _, name := range gen.FileNames() {
+				if err := writeOutput(filepath.Join(outputPath, filepath.FromSlash(name)), files[name], options); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		doc, err = gen.Generate(result)
+	case "html":
+		doc, err = gen.GenerateHTML(result)
+	case "text":
+		doc = gen.GenerateText(result)
+	case "json":
+		doc, err = gen.GenerateJSON(result)
+	case "sarif":
+		doc, err = gen.GenerateSARIF(result)
+	default:
+		return fmt.Errorf("unknown format %q (expected markdown, html, text, json or sarif)", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate documentation: %w", err)
+	}
+
+	if toClipboard {
+		return clipboard.Write(doc)
+	}
+	return writeOutput(outputPath, doc, options)
+}
+
+// writeOptions control how writeOutput writes a document
+type writeOptions struct {
+	splice bool // Replace only the section between repo-sage markers of an existing file
+	force  bool // Overwrite files not generated by repo-sage without asking
+	crlf   bool // Write CRLF line endings instead of LF
+	bom    bool // Start the file with a UTF-8 byte order mark
+}
+
+// writeOutput writes a generated document, creating parent directories such as
+// docs/. With splice set, an existing file containing repo-sage markers only has
+// the section between them replaced, preserving hand-written content around it.
+// Any other existing file that was not generated by repo-sage is only replaced
+// with force set or after the user confirms.
+func writeOutput(path, doc string, options writeOptions) error {
+	if existing, err := os.ReadFile(path); err == nil {
+		spliced, ok := "", false
+		if options.splice {
+			spliced, ok = generator.Splice(string(existing), doc)
+		}
+		switch {
+		case ok:
+			slog.Info(fmt.Sprintf("Updating the generated section of %s", path))
+			doc = spliced
+		case !options.force && !generator.IsGenerated(string(existing)):
+			if err := confirmOverwrite(path); err != nil {
+				return err
+			}
+		}
+	}
+	doc = encodeOutput(doc, options.crlf, options.bom)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// utf8BOM is the UTF-8 byte order mark some Windows tools expect
+const utf8BOM = "\ufeff"
+
+// encodeOutput sets the line endings and byte order mark of a document. A
+// spliced document may carry either from the existing file, so both are
+// normalized first.
+func encodeOutput(doc string, crlf, bom bool) string {
+	doc = strings.TrimPrefix(doc, utf8BOM)
+	doc = strings.ReplaceAll(doc, "\r\n", "\n")
+	if crlf {
+		doc = strings.ReplaceAll(doc, "\n", "\r\n")
+	}
+	if bom {
+		doc = utf8BOM + doc
+	}
+	return doc
+}
+
+// confirmOverwrite asks before replacing a file repo-sage did not write. Without
+// a terminal to ask on, it refuses.
+func confirmOverwrite(path string) error {
+	if !render.IsTerminal(os.Stdin) {
+		return fmt.Errorf("%s exists and was not generated by repo-sage; use --force to overwrite it", path)
+	}
+	fmt.Fprintf(os.Stderr, "%s exists and was not generated by repo-sage. Overwrite it? [y/N] ", path)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		// Input closed without an answer counts as no
+		fmt.Fprintln(os.Stderr)
+		return fmt.Errorf("not overwriting %s; use --force to overwrite it", path)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return fmt.Errorf("not overwriting %s; use --force to overwrite it", path)
+}
+
+// isDirOutput reports whether the output path names a directory, either because
+// it already exists as one or because it ends with a path separator
+func isDirOutput(path string) bool {
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(os.PathSeparator)) {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// checkModel verifies the endpoint is reachable and warns when it does not serve the model
+func checkModel(a analyzer.Analyzer, model string) error {
+	if model == "" {
+		model = llm.DefaultModel
+	}
+
+	models, err := a.ListModels()
+	if err != nil {
+		return fmt.Errorf("endpoint check failed: %w", err)
+	}
+
+	for _, m := range models {
+		if m == model {
+			slog.Info(fmt.Sprintf("✅ Model %q is available", model))
+			return nil
+		}
+	}
+
+	slog.Warn(fmt.Sprintf("model %q is not listed by the endpoint; run 'repo-sage config models' to see available models", model))
+	return nil
+}
+
+// doctorCheck is one line of the doctor report; Err is nil when the check passed
+type doctorCheck struct {
+	Name   string
+	Err    error
+	Detail string
+}
+
+// checkReachable verifies the API base answers HTTP requests. Any response,
+// even an error status, shows the host is reachable.
+func checkReachable(apiBase string) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(strings.TrimRight(apiBase, "/") + "/models")
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", apiBase, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// checkKeyFormat catches keys that cannot be right: empty, padded with
+// whitespace, or missing the "sk-" prefix on OpenAI's own endpoint. Other
+// endpoints use their own formats, and local servers accept anything.
+func checkKeyFormat(apiBase, key string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("no API key set")
+	case strings.TrimSpace(key) != key || strings.ContainsAny(key, " \t\r\n"):
+		return fmt.Errorf("API key contains whitespace; check for a stray space or newline when pasting")
+	case strings.Contains(apiBase, "api.openai.com") && !strings.HasPrefix(key, "sk-"):
+		return fmt.Errorf("OpenAI API keys start with \"sk-\"")
+	}
+	return nil
+}
+
+// checkModelAccess verifies the key is accepted and the endpoint serves the profile's model
+func checkModelAccess(profile config.Profile) error {
+	model := profile.Model
+	if model == "" {
+		model = llm.DefaultModel
+	}
+
+	a, err := analyzer.NewAnalyzer(analyzer.AnalyzeOptions{
+		OpenAIKey: profile.APIKey,
+		APIBase:   profile.APIBase,
+		Model:     model,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create analyzer: %w", err)
+	}
+	models, err := a.ListModels()
+	if err != nil {
+		return err
+	}
+	for _, m := range models {
+		if m == model {
+			return nil
+		}
+	}
+	return fmt.Errorf("model %q is not listed by the endpoint; run 'repo-sage config models' to see available models", model)
+}
+
+// gitVersion returns the installed git version, e.g. "git version 2.43.0"
+func gitVersion() (string, error) {
+	out, err := exec.Command("git", "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("git not found on PATH: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Environment variables that configure an ephemeral profile without a config file
+const (
+	envAPIBase = "REPO_SAGE_API_BASE"
+	envAPIKey  = "REPO_SAGE_API_KEY"
+	envModel   = "REPO_SAGE_MODEL"
+)
+
+// addProfileFlags registers the flags used to select or construct an LLM profile
+func addProfileFlags(cmd *cobra.Command) {
+	cmd.Flags().String("profile", "", "Profile to use for LLM operations")
+	cmd.Flags().String("api-base", "", "API base URL, overriding profiles (env "+envAPIBase+")")
+	cmd.Flags().String("api-key", "", "API key, overriding profiles (env "+envAPIKey+")")
+	cmd.Flags().String("model", "", "Model name, overriding profiles (env "+envModel+")")
+}
+
+// resolveProfile builds an ephemeral profile when an API key is given by flag or
+// environment, and otherwise loads the selected or default profile from config
+func resolveProfile(cmd *cobra.Command) (config.Profile, error) {
+	profileName, _ := cmd.Flags().GetString("profile")
+	apiBase := flagOrEnv(cmd, "api-base", envAPIBase)
+	apiKey := flagOrEnv(cmd, "api-key", envAPIKey)
+	model := flagOrEnv(cmd, "model", envModel)
+
+	if apiKey != "" && profileName == "" {
+		return config.Profile{
+			APIBase: apiBase,
+			APIKey:  apiKey,
+			Model:   model,
+		}, nil
+	}
+
+	profile, err := loadProfile(profileName)
+	if err != nil {
+		return config.Profile{}, err
+	}
+
+	// Explicit values still override individual fields of a stored profile
+	if apiBase != "" {
+		profile.APIBase = apiBase
+	}
+	if apiKey != "" {
+		profile.APIKey = apiKey
+	}
+	if model != "" {
+		profile.Model = model
+	}
+	return profile, nil
+}
+
+// resolveOutput returns the --output flag when given, otherwise the default_output
+// configured for the profile or globally, otherwise the flag's default
+func resolveOutput(cmd *cobra.Command, profile config.Profile) (string, error) {
+	outputPath, _ := cmd.Flags().GetString("output")
+	if cmd.Flags().Changed("output") {
+		return outputPath, nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+	if configured := cfg.DefaultOutputFor(profile); configured != "" {
+		return configured, nil
+	}
+	return outputPath, nil
+}
+
+// resolveLanguage returns the --lang flag when given, otherwise the language
+// configured globally. An empty result means English.
+func resolveLanguage(cmd *cobra.Command) (string, error) {
+	language, _ := cmd.Flags().GetString("lang")
+	if cmd.Flags().Changed("lang") {
+		return language, nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+	return cfg.Language, nil
+}
+
+// resolveDocFiles returns the --doc-file patterns when given, otherwise the
+// doc_files configured globally. A nil result uses analyzer.DefaultDocFiles;
+// an empty one, from --doc-file= or an empty list, includes no documentation.
+func resolveDocFiles(cmd *cobra.Command) ([]string, error) {
+	if cmd.Flags().Changed("doc-file") {
+		patterns, _ := cmd.Flags().GetStringArray("doc-file")
+		kept := []string{}
+		for _, p := range patterns {
+			if p != "" {
+				kept = append(kept, p)
+			}
+		}
+		return kept, nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return cfg.DocFiles, nil
+}
+
+// resolveFooter returns the --footer and --no-footer flags when either is
+// given, otherwise the footer and no_footer configured globally. An empty text
+// keeps the default footer.
+func resolveFooter(cmd *cobra.Command) (text string, disabled bool, err error) {
+	text, _ = cmd.Flags().GetString("footer")
+	disabled, _ = cmd.Flags().GetBool("no-footer")
+	if cmd.Flags().Changed("footer") || cmd.Flags().Changed("no-footer") {
+		return text, disabled, nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to load config: %w", err)
+	}
+	return cfg.Footer, cfg.NoFooter, nil
+}
+
+// runContext returns the context of a command's run, which expires after
+// --timeout; a timeout of 0 never expires
+func runContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// timeoutError replaces err, which may only mention a failed request, with one
+// naming --timeout when the run's context expired
+func timeoutError(ctx context.Context, cmd *cobra.Command, err error) error {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	return fmt.Errorf("stopped after --timeout %s: %w", timeout, context.DeadlineExceeded)
+}
+
+// checkClipboard reports whether --clipboard was given, failing when no
+// clipboard tool is installed so that this shows before any slow LLM work
+func checkClipboard(cmd *cobra.Command) (bool, error) {
+	toClipboard, _ := cmd.Flags().GetBool("clipboard")
+	if !toClipboard {
+		return false, nil
+	}
+	return true, clipboard.Available()
+}
+
+// writeExplanation copies an explanation to the clipboard with --clipboard and
+// otherwise renders it, unless --stream already printed it
+func writeExplanation(cmd *cobra.Command, explanation string, streamed bool, mode render.Mode) error {
+	if toClipboard, _ := cmd.Flags().GetBool("clipboard"); toClipboard {
+		if err := clipboard.Write(explanation); err != nil {
+			return err
+		}
+		slog.Info("📋 Explanation copied to the clipboard")
+		return nil
+	}
+	if !streamed {
+		render.Write(explanation, mode)
+	}
+	return nil
+}
+
+// registerLanguages adds the extension to language mappings of the config file.
+// A config file that cannot be loaded is left for the commands that need it to
+// report, so that the config commands can still fix it.
+func registerLanguages() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		slog.Debug("skipping custom languages", "error", err)
+		return nil
+	}
+	if err := git.RegisterLanguages(cfg.Languages); err != nil {
+		return fmt.Errorf("invalid languages in config: %w", err)
+	}
+	return nil
+}
+
+// resolveMode reads --mode and its --detailed shorthand, reporting whether to
+// run a detailed analysis or only ask for a summary
+func resolveMode(cmd *cobra.Command) (detailed, summaryOnly bool, err error) {
+	mode, _ := cmd.Flags().GetString("mode")
+	if d, _ := cmd.Flags().GetBool("detailed"); d {
+		if cmd.Flags().Changed("mode") && mode != "detailed" {
+			return false, false, fmt.Errorf("--detailed conflicts with --mode %s", mode)
+		}
+		mode = "detailed"
+	}
+
+	switch mode {
+	case "quick":
+		return false, true, nil
+	case "standard":
+		return false, false, nil
+	case "detailed":
+		return true, false, nil
+	}
+	return false, false, fmt.Errorf("unknown mode %q (expected quick, standard or detailed)", mode)
+}
+
+// applyRepoDefaults sets the analyze flags not given on the command line from
+// the repository's .repo-sage.yaml. The values then count as given, so they
+// also take precedence over the default_output of the user's config.
+func applyRepoDefaults(cmd *cobra.Command, repoPath string) error {
+	repoConfig, err := config.LoadRepoConfig(repoPath)
+	if err != nil {
+		return err
+	}
+	defaults := repoConfig.Analyze
+
+	// A flag given on the command line wins over the repository's value
+	applied := 0
+	set := func(name, value string) error {
+		if value == "" || cmd.Flags().Changed(name) {
+			return nil
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s in %s: %w", name, config.RepoConfigFile, err)
+		}
+		applied++
+		return nil
+	}
+
+	output := defaults.Output
+	if output != "" && !filepath.IsAbs(output) {
+		output = filepath.Join(repoPath, output)
+	}
+	contextSize := ""
+	if defaults.Context > 0 {
+		contextSize = strconv.Itoa(defaults.Context)
+	}
+	// --detailed is shorthand for --mode detailed, so it replaces the mode too
+	if !cmd.Flags().Changed("detailed") {
+		if err := set("mode", defaults.Mode); err != nil {
+			return err
+		}
+	}
+	for _, flag := range [][2]string{{"output", output}, {"format", defaults.Format}, {"context", contextSize}} {
+		if err := set(flag[0], flag[1]); err != nil {
+			return err
+		}
+	}
+	if !cmd.Flags().Changed("ignore-dir") {
+		for _, dir := range defaults.IgnoreDirs {
+			if err := cmd.Flags().Set("ignore-dir", dir); err != nil {
+				return err
+			}
+			applied++
+		}
+	}
+
+	if applied > 0 {
+		slog.Debug("applied repository defaults", "file", config.RepoConfigFile)
+	}
+	return nil
+}
+
+// flagOrEnv returns the flag value if set, falling back to the environment variable
+func flagOrEnv(cmd *cobra.Command, flag, env string) string {
+	if value, _ := cmd.Flags().GetString(flag); value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+// loadProfile returns the named profile, or the default profile when name is empty
+func loadProfile(name string) (config.Profile, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return config.Profile{}, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var profile config.Profile
+	if name != "" {
+		var exists bool
+		if profile, exists = cfg.GetProfile(name); !exists {
+			return config.Profile{}, fmt.Errorf("profile %q not found", name)
+		}
+	} else if profile, name, err = cfg.GetDefaultProfile(); err != nil {
+		return config.Profile{}, fmt.Errorf("no profile configured. Run 'repo-sage config add-profile' or set %s to get started", envAPIKey)
+	}
+
+	// Only the profile in use reads its env:NAME references
+	profile, err = profile.Resolve()
+	if err != nil {
+		return config.Profile{}, fmt.Errorf("failed to load profile %q: %w", name, err)
+	}
+	return profile, nil
+}
+
+// formatBytes formats a size in bytes with a binary unit, e.g. "1.5 MiB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatLanguageShares lists languages by share, largest first, e.g. "Go 80.0%, Shell 20.0%"
+func formatLanguageShares(languages map[string]float64) string {
+	names := make([]string, 0, len(languages))
+	for lang := range languages {
+		names = append(names, lang)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if languages[names[i]] == languages[names[j]] {
+			return names[i] < names[j]
+		}
+		return languages[names[i]] > languages[names[j]]
+	})
+
+	parts := make([]string, len(names))
+	for i, lang := range names {
+		parts[i] = fmt.Sprintf("%s %.1f%%", lang, languages[lang])
+	}
+	return strings.Join(parts, ", ")
+}
+
 func maskAPIKey(key string) string {
+	// An env:NAME reference names the secret without revealing it
+	if config.IsEnvRef(key) {
+		return key
+	}
 	if len(key) <= 8 {
 		return "********"
 	}
@@ -267,42 +1813,253 @@ func maskAPIKey(key string) string {
 }
 
 func init() {
+	// Global flags
+	rootCmd.PersistentFlags().String("config", "", "Config file to use instead of ~/.repo-sage/config.yaml (env "+config.EnvConfigPath+")")
+	rootCmd.PersistentFlags().String("log-level", "info", "Log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().Bool("no-emoji", false, "Strip emoji from progress output and generated headings")
+	rootCmd.PersistentFlags().Bool("json-logs", false, "Write logs and progress to stderr as JSON lines")
+	rootCmd.PersistentFlags().Bool("no-progress", false, "Hide progress bars and status messages, showing only warnings and errors")
+	rootCmd.PersistentFlags().Int("concurrency", 0, "Maximum parallel file reads and LLM requests (default: one file read per CPU, 4 LLM requests)")
+
 	// Analyze command flags
 	analyzeCmd.Flags().StringP("repo", "r", "", "Path to the Git repository")
-	analyzeCmd.Flags().StringP("output", "o", "SUMMARY.md", "Output file path")
-	analyzeCmd.Flags().String("profile", "", "Profile to use for LLM operations")
+	analyzeCmd.Flags().StringP("output", "o", "SUMMARY.md", "Output file path, or a directory (e.g. docs/) to split by section (default_output in the config overrides the default)")
+	addProfileFlags(analyzeCmd)
 	analyzeCmd.Flags().Int("context", 4000, "Context size for AI analysis")
-	analyzeCmd.Flags().Bool("detailed", false, "Perform detailed code analysis")
+	analyzeCmd.Flags().String("mode", "standard", "Analysis mode: quick (one-paragraph summary), standard or detailed")
+	analyzeCmd.Flags().Bool("detailed", false, "Perform detailed code analysis (same as --mode detailed)")
+	analyzeCmd.Flags().Int("chunk-size", 0, "Characters per chunk in detailed analysis (default 3/8 of --context, 1500 for 4000)")
+	analyzeCmd.Flags().Int("chunk-overlap", 150, "Characters of each chunk repeated at the start of the next in detailed analysis")
+	analyzeCmd.Flags().String("keep-chunks", "", "Directory to write each chunk's prompt and response to in detailed analysis")
+	analyzeCmd.Flags().Bool("chunk-appendix", false, "Append each chunk's analysis to the generated documentation in detailed analysis")
+	analyzeCmd.Flags().Bool("ci-summary", false, "Ask the model to summarize how the detected CI/CD configuration builds and deploys the project (one extra request)")
+	analyzeCmd.Flags().Bool("group-languages", false, "Merge related languages in the statistics: JavaScript, TypeScript and React as JavaScript/TypeScript, C, C++ and headers as C/C++, SASS as CSS")
+	analyzeCmd.Flags().Bool("data-model", false, "Ask the model to summarize the data model from the detected schema and migration files (one extra request)")
+	analyzeCmd.Flags().Bool("since-tag", false, "Add a section summarizing the commits since the most recent tag, for release docs (one extra request)")
+	analyzeCmd.Flags().Bool("file-summaries", false, "Add a table with a one-sentence summary of each significant file in detailed analysis, batched to bound cost")
+	analyzeCmd.Flags().Bool("all-files", false, "Send every file in detailed analysis, including lock files and other non-source files")
+	analyzeCmd.Flags().Int("max-continuations", 3, "Maximum follow-up requests when a response is truncated")
+	analyzeCmd.Flags().Duration("timeout", 0, "Abort the whole run if it takes longer than this, e.g. 10m (0 for no limit)")
+	analyzeCmd.Flags().Int("token-budget", 0, "Stop sending requests once about this many tokens are used, keeping partial results (0 for no limit)")
+	analyzeCmd.Flags().StringArray("context-file", nil, "File to always include in the analysis (repeatable)")
+	analyzeCmd.Flags().StringArray("doc-file", nil, "Documentation file pattern, e.g. \"docs/*.md\", that seeds the summary in place of the defaults such as ARCHITECTURE.md (repeatable; --doc-file= for none; doc_files in the config sets a default)")
+	analyzeCmd.Flags().StringArray("ignore-dir", nil, "Additional directory name to skip, e.g. generated (repeatable)")
+	analyzeCmd.Flags().String("file-source", "git", "How to list files: git lists files tracked in the index, leaving out gitignored output and new untracked files; worktree walks every file on disk, including uncommitted and gitignored ones. git falls back to worktree without an index")
+	analyzeCmd.Flags().Bool("include-untracked", false, "With --file-source git, also list untracked files that are not gitignored, e.g. new files not committed yet")
+	analyzeCmd.Flags().Bool("no-git", false, "Read a plain directory, such as an extracted tarball, without git; git features such as --rev and --since are unavailable")
+	analyzeCmd.Flags().StringArray("include-sensitive", nil, "Sensitive-file pattern to stop excluding, e.g. \"*.key\" (repeatable)")
+	analyzeCmd.Flags().Int("depth", 3, "Directory levels shown to the model before collapsing deeper ones (0 for all)")
+	analyzeCmd.Flags().String("since", "", "Only analyze files changed since a commit or date (e.g. v1.2.0, \"2 weeks ago\")")
+	analyzeCmd.Flags().String("affected-since", "", "Only analyze the Go packages changed since a commit (e.g. main) and the packages that import them, directly or transitively")
+	analyzeCmd.MarkFlagsMutuallyExclusive("since", "affected-since")
+	analyzeCmd.Flags().String("rev", "", "Analyze the repository as of a commit, tag or branch instead of the working tree")
+	analyzeCmd.Flags().String("path", "", "Only analyze this subdirectory of the repository, e.g. internal; paths stay relative to the root")
+	analyzeCmd.Flags().Bool("resume", false, "Resume an interrupted detailed analysis from its saved chunk results")
+	analyzeCmd.Flags().String("format", "markdown", "Output format (markdown, html, text, json, sarif)")
+	analyzeCmd.Flags().StringArray("component-type", nil, "Only document components of this type, e.g. API (repeatable)")
+	analyzeCmd.Flags().Bool("show-structure", false, "Add a Project Structure section with the directory tree")
+	analyzeCmd.Flags().String("footer", "", "Text replacing the \"Generated by repo-sage\" line of the footer")
+	analyzeCmd.Flags().Bool("no-footer", false, "Leave the footer out of Markdown and HTML documents, and the signature out of text output")
+	analyzeCmd.Flags().Bool("clipboard", false, "Copy the documentation to the system clipboard instead of writing --output")
+	analyzeCmd.Flags().Bool("force", false, "Overwrite existing output that was not generated by repo-sage without asking")
+	analyzeCmd.Flags().String("line-endings", "lf", "Line endings of Markdown and HTML output: lf or crlf")
+	analyzeCmd.Flags().Bool("bom", false, "Start Markdown and HTML output with a UTF-8 byte order mark")
+	analyzeCmd.Flags().Bool("frontmatter", false, "Start Markdown pages with YAML frontmatter (title and date) for MkDocs or Docusaurus")
+	analyzeCmd.Flags().String("output-template-dir", "", "Directory of templates, one per file written to a directory --output, replacing the built-in pages; a trailing .tmpl is dropped from the file names")
+	analyzeCmd.Flags().StringArray("frontmatter-tag", nil, "Tag to list in the Markdown frontmatter, e.g. architecture (repeatable; implies --frontmatter)")
+	analyzeCmd.Flags().String("save-result", "", "Also save the raw analysis result as JSON for 'repo-sage generate'")
+	analyzeCmd.Flags().Bool("no-redact", false, "Send file contents without redacting likely secrets such as API keys and passwords")
+	analyzeCmd.Flags().String("lang", "", "Natural language to write the documentation in, e.g. Spanish (language in the config sets a default)")
+	analyzeCmd.Flags().String("prompt-suffix", "", "Extra instructions appended to every prompt, e.g. \"Focus on security\"")
+	analyzeCmd.Flags().Bool("check", false, "Verify the endpoint is reachable and serves the model before analyzing")
 	analyzeCmd.MarkFlagRequired("repo")
 
 	// Explain command flags
 	explainCmd.Flags().StringP("file", "f", "", "Path to the file to explain")
-	explainCmd.Flags().String("profile", "", "Profile to use for LLM operations")
+	addProfileFlags(explainCmd)
 	explainCmd.Flags().Int("context", 4000, "Context size for AI analysis")
+	explainCmd.Flags().Int("max-continuations", 3, "Maximum follow-up requests when a response is truncated")
+	explainCmd.Flags().Duration("timeout", 0, "Abort the whole run if it takes longer than this, e.g. 10m (0 for no limit)")
+	explainCmd.Flags().String("render", "markdown", "Output rendering (markdown, plain)")
+	explainCmd.Flags().String("symbol", "", "Explain only this function or type (Type.Method for Go methods)")
+	explainCmd.Flags().Int("line", 0, "Explain only the declaration spanning this line")
+	explainCmd.Flags().Bool("no-redact", false, "Send file contents without redacting likely secrets such as API keys and passwords")
+	explainCmd.Flags().String("lang", "", "Natural language to write the explanation in, e.g. Japanese (language in the config sets a default)")
+	explainCmd.Flags().String("prompt-suffix", "", "Extra instructions appended to the prompt, e.g. \"Assume the reader is a new hire\"")
+	explainCmd.Flags().Bool("no-cache", false, "Ask the model again even if this content was explained before")
+	explainCmd.Flags().Bool("stream", false, "Print the explanation as it is generated, without terminal styling")
+	explainCmd.Flags().Bool("clipboard", false, "Copy the explanation to the system clipboard instead of printing it")
 	explainCmd.MarkFlagRequired("file")
 
+	// Explain-diff command flags
+	explainDiffCmd.Flags().StringP("file", "f", "", "Path to the file whose changes to explain")
+	explainDiffCmd.Flags().String("base", "", "Commit, tag or branch to compare against, e.g. main")
+	explainDiffCmd.Flags().String("head", "", "Commit, tag or branch of the new version (defaults to the working tree)")
+	addProfileFlags(explainDiffCmd)
+	explainDiffCmd.Flags().Int("context", 4000, "Context size for AI analysis")
+	explainDiffCmd.Flags().Int("max-continuations", 3, "Maximum follow-up requests when a response is truncated")
+	explainDiffCmd.Flags().Duration("timeout", 0, "Abort the whole run if it takes longer than this, e.g. 10m (0 for no limit)")
+	explainDiffCmd.Flags().String("render", "markdown", "Output rendering (markdown, plain)")
+	explainDiffCmd.Flags().Bool("no-redact", false, "Send file contents without redacting likely secrets such as API keys and passwords")
+	explainDiffCmd.Flags().String("lang", "", "Natural language to write the explanation in, e.g. Japanese (language in the config sets a default)")
+	explainDiffCmd.Flags().String("prompt-suffix", "", "Extra instructions appended to the prompt, e.g. \"Focus on API compatibility\"")
+	explainDiffCmd.Flags().Bool("stream", false, "Print the explanation as it is generated, without terminal styling")
+	explainDiffCmd.Flags().Bool("clipboard", false, "Copy the explanation to the system clipboard instead of printing it")
+	explainDiffCmd.MarkFlagRequired("file")
+	explainDiffCmd.MarkFlagRequired("base")
+
+	// Generate command flags
+	generateCmd.Flags().String("from", "", "Path to an analysis result saved with --save-result")
+	generateCmd.Flags().StringP("output", "o", "SUMMARY.md", "Output file path, or a directory (e.g. docs/) to split Markdown by section (default_output in the config overrides the default)")
+	generateCmd.Flags().String("format", "markdown", "Output format (markdown, html, text, json, sarif)")
+	generateCmd.Flags().StringArray("component-type", nil, "Only document components of this type, e.g. API (repeatable)")
+	generateCmd.Flags().Bool("show-structure", false, "Add a Project Structure section with the directory tree")
+	generateCmd.Flags().String("footer", "", "Text replacing the \"Generated by repo-sage\" line of the footer")
+	generateCmd.Flags().Bool("no-footer", false, "Leave the footer out of Markdown and HTML documents, and the signature out of text output")
+	generateCmd.Flags().Bool("clipboard", false, "Copy the documentation to the system clipboard instead of writing --output")
+	generateCmd.Flags().Bool("force", false, "Overwrite existing output that was not generated by repo-sage without asking")
+	generateCmd.Flags().String("line-endings", "lf", "Line endings of Markdown and HTML output: lf or crlf")
+	generateCmd.Flags().Bool("bom", false, "Start Markdown and HTML output with a UTF-8 byte order mark")
+	generateCmd.Flags().Bool("frontmatter", false, "Start Markdown pages with YAML frontmatter (title and date) for MkDocs or Docusaurus")
+	generateCmd.Flags().String("output-template-dir", "", "Directory of templates, one per file written to a directory --output, replacing the built-in pages; a trailing .tmpl is dropped from the file names")
+	generateCmd.Flags().StringArray("frontmatter-tag", nil, "Tag to list in the Markdown frontmatter, e.g. architecture (repeatable; implies --frontmatter)")
+	generateCmd.MarkFlagRequired("from")
+
+	// Compare command flags
+	compareCmd.Flags().String("render", "markdown", "Output rendering (markdown, plain)")
+
+	// Components command flags
+	componentsCmd.Flags().StringP("repo", "r", "", "Path to the Git repository")
+	addProfileFlags(componentsCmd)
+	componentsCmd.Flags().Int("context", 4000, "Context size for AI analysis")
+	componentsCmd.Flags().Int("max-continuations", 3, "Maximum follow-up requests when a response is truncated")
+	componentsCmd.Flags().String("format", "table", "Output format (table, json, or jsonl to print each component as soon as it is identified)")
+	componentsCmd.Flags().StringArray("context-file", nil, "File to always include in the analysis (repeatable)")
+	componentsCmd.Flags().StringArray("doc-file", nil, "Documentation file pattern, e.g. \"docs/*.md\", that seeds the summary in place of the defaults such as ARCHITECTURE.md (repeatable; --doc-file= for none; doc_files in the config sets a default)")
+	componentsCmd.Flags().StringArray("ignore-dir", nil, "Additional directory name to skip, e.g. generated (repeatable)")
+	componentsCmd.Flags().String("file-source", "git", "How to list files: git lists files tracked in the index, leaving out gitignored output and new untracked files; worktree walks every file on disk, including uncommitted and gitignored ones. git falls back to worktree without an index")
+	componentsCmd.Flags().Bool("include-untracked", false, "With --file-source git, also list untracked files that are not gitignored, e.g. new files not committed yet")
+	componentsCmd.Flags().Bool("no-git", false, "Read a plain directory, such as an extracted tarball, without git; git features such as --rev and --since are unavailable")
+	componentsCmd.Flags().StringArray("include-sensitive", nil, "Sensitive-file pattern to stop excluding, e.g. \"*.key\" (repeatable)")
+	componentsCmd.Flags().Int("depth", 3, "Directory levels shown to the model before collapsing deeper ones (0 for all)")
+	componentsCmd.Flags().Bool("no-redact", false, "Send file contents without redacting likely secrets such as API keys and passwords")
+	componentsCmd.MarkFlagRequired("repo")
+
+	// Changelog command flags
+	changelogCmd.Flags().StringP("repo", "r", ".", "Path to the Git repository")
+	changelogCmd.Flags().StringP("output", "o", "", "Output file path (defaults to stdout)")
+	addProfileFlags(changelogCmd)
+	changelogCmd.Flags().Int("context", 4000, "Context size for AI analysis")
+	changelogCmd.Flags().Int("max-continuations", 3, "Maximum follow-up requests when a response is truncated")
+	changelogCmd.Flags().IntP("count", "n", 20, "Number of recent commits to summarize")
+	changelogCmd.Flags().String("range", "", "Revision range to summarize (e.g. v1.0.0..HEAD)")
+	changelogCmd.Flags().Bool("diffs", false, "Include truncated diffs for more accurate summaries")
+
+	// Chat command flags
+	chatCmd.Flags().StringP("repo", "r", "", "Path to the Git repository")
+	addProfileFlags(chatCmd)
+	chatCmd.Flags().Int("context", 4000, "Context size for AI analysis; about half is used for the files sent with each question")
+	chatCmd.Flags().Int("max-continuations", 3, "Maximum follow-up requests when a response is truncated")
+	chatCmd.Flags().String("render", "markdown", "Output rendering (markdown, plain)")
+	chatCmd.Flags().StringArray("ignore-dir", nil, "Additional directory name to skip, e.g. generated (repeatable)")
+	chatCmd.Flags().String("file-source", "git", "How to list files: git lists files tracked in the index, leaving out gitignored output and new untracked files; worktree walks every file on disk, including uncommitted and gitignored ones. git falls back to worktree without an index")
+	chatCmd.Flags().Bool("include-untracked", false, "With --file-source git, also list untracked files that are not gitignored, e.g. new files not committed yet")
+	chatCmd.Flags().Bool("no-git", false, "Read a plain directory, such as an extracted tarball, without git; git features such as --rev and --since are unavailable")
+	chatCmd.Flags().StringArray("include-sensitive", nil, "Sensitive-file pattern to stop excluding, e.g. \"*.key\" (repeatable)")
+	chatCmd.Flags().Bool("no-redact", false, "Send file contents without redacting likely secrets such as API keys and passwords")
+	chatCmd.Flags().String("lang", "", "Natural language to answer in, e.g. German (language in the config sets a default)")
+	chatCmd.Flags().String("prompt-suffix", "", "Extra instructions for every answer, e.g. \"Answer briefly\"")
+	chatCmd.MarkFlagRequired("repo")
+
+	// Stats command flags
+	statsCmd.Flags().StringP("repo", "r", "", "Path to the Git repository")
+	statsCmd.Flags().Int("context", 4000, "Context size used to derive the chunk size")
+	statsCmd.Flags().Int("chunk-size", 0, "Characters per chunk in detailed analysis (default 3/8 of --context, 1500 for 4000)")
+	statsCmd.Flags().Int("chunk-overlap", 150, "Characters of each chunk repeated at the start of the next in detailed analysis")
+	statsCmd.Flags().Bool("all-files", false, "Count every file for detailed analysis, including lock files and other non-source files")
+	statsCmd.Flags().Bool("file-summaries", false, "Include the file summary requests of --file-summaries in the detailed estimate")
+	statsCmd.Flags().StringArray("context-file", nil, "File to always include in the analysis (repeatable)")
+	statsCmd.Flags().StringArray("doc-file", nil, "Documentation file pattern, e.g. \"docs/*.md\", that seeds the summary in place of the defaults such as ARCHITECTURE.md (repeatable; --doc-file= for none; doc_files in the config sets a default)")
+	statsCmd.Flags().StringArray("ignore-dir", nil, "Additional directory name to skip, e.g. generated (repeatable)")
+	statsCmd.Flags().String("file-source", "git", "How to list files: git lists files tracked in the index, leaving out gitignored output and new untracked files; worktree walks every file on disk, including uncommitted and gitignored ones. git falls back to worktree without an index")
+	statsCmd.Flags().Bool("include-untracked", false, "With --file-source git, also list untracked files that are not gitignored, e.g. new files not committed yet")
+	statsCmd.Flags().Bool("no-git", false, "Read a plain directory, such as an extracted tarball, without git; git features such as --rev and --since are unavailable")
+	statsCmd.Flags().StringArray("include-sensitive", nil, "Sensitive-file pattern to stop excluding, e.g. \"*.key\" (repeatable)")
+	statsCmd.Flags().Int("depth", 3, "Directory levels shown to the model before collapsing deeper ones (0 for all)")
+	statsCmd.Flags().String("since", "", "Only count files changed since a commit or date (e.g. v1.2.0, \"2 weeks ago\")")
+	statsCmd.Flags().String("affected-since", "", "Only count the Go packages changed since a commit (e.g. main) and the packages that import them, directly or transitively")
+	statsCmd.MarkFlagsMutuallyExclusive("since", "affected-since")
+	statsCmd.Flags().String("rev", "", "Count files as of a commit, tag or branch instead of the working tree")
+	statsCmd.Flags().String("path", "", "Only count files in this subdirectory of the repository, e.g. internal")
+	statsCmd.Flags().Float64("cost-per-mtok", 0, "Price in USD per million input tokens, to estimate the cost of each mode")
+	statsCmd.Flags().Bool("group-languages", false, "Merge related languages in the statistics: JavaScript, TypeScript and React as JavaScript/TypeScript, C, C++ and headers as C/C++, SASS as CSS")
+	statsCmd.Flags().String("format", "table", "Output format (table, json)")
+	statsCmd.MarkFlagRequired("repo")
+
 	// Add commands to root
 	rootCmd.AddCommand(analyzeCmd)
 	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(explainDiffCmd)
+	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(compareCmd)
+	rootCmd.AddCommand(componentsCmd)
+	rootCmd.AddCommand(changelogCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(chatCmd)
+	rootCmd.AddCommand(doctorCmd)
 
 	// Add config commands
 	rootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(addProfileCmd)
+	configCmd.AddCommand(copyProfileCmd)
 	configCmd.AddCommand(listProfilesCmd)
 	configCmd.AddCommand(useProfileCmd)
+	configCmd.AddCommand(modelsCmd)
 
 	addProfileCmd.Flags().String("api-base", "", "API base URL for the LLM endpoint")
 	addProfileCmd.Flags().String("api-key", "", "API key for authentication")
 	addProfileCmd.Flags().String("model", "", "Model name to use")
+	addProfileCmd.Flags().String("default-output", "", "Output path used by analyze with this profile when --output is not given")
+	addProfileCmd.Flags().Int("requests-per-minute", 0, "Pace requests made with this profile to stay under the provider's rate limit (0 for no limit)")
 
 	addProfileCmd.MarkFlagRequired("api-base")
 	addProfileCmd.MarkFlagRequired("api-key")
 	addProfileCmd.MarkFlagRequired("model")
+
+	copyProfileCmd.Flags().String("model", "", "Model name for the copy, instead of the source profile's")
+
+	addProfileFlags(modelsCmd)
+	addProfileFlags(doctorCmd)
 }
 
 func main() {
+	// Install the default handler so errors raised before flag parsing are formatted consistently
+	_ = logging.Setup(logging.Options{Level: "info"})
+
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		logError(err)
 		os.Exit(1)
 	}
 }
+
+// logError logs an error with guidance on fixing it when the endpoint said why
+// it rejected a request
+func logError(err error) {
+	slog.Error(err.Error())
+	switch {
+	case errors.Is(err, llm.ErrUnauthorized):
+		slog.Info("Check your API key: pass --api-key, set " + envAPIKey + " or update the profile with 'repo-sage config add-profile'")
+	case errors.Is(err, llm.ErrRateLimited):
+		slog.Info("The endpoint is rate limiting requests; wait a moment and retry, or lower --concurrency")
+	case errors.Is(err, llm.ErrModelNotFound):
+		slog.Info("Run 'repo-sage config models' to list the models the endpoint serves, then pass one with --model")
+	case errors.Is(err, llm.ErrContextLengthExceeded):
+		slog.Info("Lower --context or use a model with a larger context window")
+	case errors.Is(err, llm.ErrUnreachable):
+		slog.Info("Pass the right endpoint with --api-base or " + envAPIBase + ", or run 'repo-sage doctor' to check the profile")
+	case errors.Is(err, context.DeadlineExceeded):
+		slog.Info("The run took longer than --timeout; raise it, or reduce the work per run, e.g. with a smaller --context")
+	case errors.Is(err, git.ErrNotRepository):
+		slog.Info("To analyze a directory without git, such as an extracted tarball, pass --no-git to analyze, components, chat or stats")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/priyupadhyay/repo-sage/internal/analyzer"
+	"github.com/priyupadhyay/repo-sage/internal/config"
+	"github.com/priyupadhyay/repo-sage/internal/generator"
+)
+
+// TestWriteDocsTextTwice checks that a second --format text run replaces the
+// output of the first, while a hand-written file is still protected
+func TestWriteDocsTextTwice(t *testing.T) {
+	dir := t.TempDir()
+	config.SetConfigPath(filepath.Join(dir, "config.yaml"))
+	t.Cleanup(func() { config.SetConfigPath("") })
+	output := filepath.Join(dir, "out.txt")
+
+	for _, description := range []string{"First run.", "Second run."} {
+		result := &analyzer.AnalysisResult{RepoInfo: analyzer.RepoInfo{Description: description}}
+		if err := writeDocs(generateCmd, result, "text", output); err != nil {
+			t.Fatalf("writeDocs (%s): %v", description, err)
+		}
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Second run.\n\n" + generator.TextSignature + "\n"; string(got) != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	// Tests have no terminal to ask on, so without --force the write is refused
+	handWritten := filepath.Join(dir, "README.txt")
+	if err := os.WriteFile(handWritten, []byte("Notes written by hand.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result := &analyzer.AnalysisResult{RepoInfo: analyzer.RepoInfo{Description: "Generated."}}
+	if err := writeDocs(generateCmd, result, "text", handWritten); err == nil {
+		t.Error("writeDocs replaced a hand-written file without --force")
+	}
+	if got, _ := os.ReadFile(handWritten); string(got) != "Notes written by hand.\n" {
+		t.Errorf("hand-written file = %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,75 @@
+package analyzer
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/priyupadhyay/repo-sage/pkg/git"
+)
+
+// filterAffectedPackages keeps the files of the Go packages changed since a
+// commit and of every package that imports one of them, directly or through
+// other packages, so only documentation the change can affect is regenerated
+func filterAffectedPackages(repo *git.Repository, files []string, since string) ([]string, error) {
+	changed, err := repo.ChangedSince(since)
+	if err != nil {
+		return nil, err
+	}
+
+	packages := parseGoPackages(repo, files)
+	modules := goModules(repo, withEnclosingModules(repo.Scope(), files))
+	isPackage := make(map[string]bool, len(packages))
+	for _, pkg := range packages {
+		isPackage[pkg.Dir] = true
+	}
+
+	// Test files do not change what a package offers its importers
+	affected := make(map[string]bool)
+	var queue []string
+	for _, file := range changed {
+		dir := path.Dir(file)
+		if path.Ext(file) != ".go" || strings.HasSuffix(file, "_test.go") || !isPackage[dir] || affected[dir] {
+			continue
+		}
+		affected[dir] = true
+		queue = append(queue, dir)
+	}
+	if len(queue) == 0 {
+		return nil, fmt.Errorf("no Go packages changed since %s", since)
+	}
+	changedCount := len(queue)
+
+	importers := make(map[string][]string) // imported directory -> importing directories
+	for _, edge := range goImportEdges(packages, modules) {
+		importers[edge[1]] = append(importers[edge[1]], edge[0])
+	}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		for _, importer := range importers[dir] {
+			if !affected[importer] {
+				affected[importer] = true
+				queue = append(queue, importer)
+			}
+		}
+	}
+
+	dirs := make([]string, 0, len(affected))
+	for dir := range affected {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	slog.Info(fmt.Sprintf("%d Go packages changed since %s, %d with their dependents", changedCount, since, len(dirs)))
+	slog.Debug("affected Go packages", "packages", dirs)
+
+	var filtered []string
+	for _, file := range files {
+		if affected[path.Dir(file)] {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered, nil
+}
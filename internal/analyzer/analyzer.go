@@ -1,53 +1,168 @@
 package analyzer
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // RepoInfo contains the analyzed repository information
 type RepoInfo struct {
-	Name         string
-	Description  string
-	Languages    map[string]float64 // language -> percentage
-	Components   []Component
-	EntryPoints  []string
-	Dependencies map[string]string // dependency -> version
+	Name         string             `json:"name"`
+	Description  string             `json:"description"`
+	Languages    map[string]float64 `json:"languages"` // language -> percentage
+	Components   []Component        `json:"components"`
+	EntryPoints  []string           `json:"entry_points"`            // Most likely first
+	Dependencies map[string]string  `json:"dependencies"`            // dependency -> version
+	License      string             `json:"license,omitempty"`       // SPDX identifier, e.g. "MIT"; empty if not recognized
+	LicenseFiles []string           `json:"license_files,omitempty"` // License files at the repository root
+	CI           []CIPipeline       `json:"ci,omitempty"`            // CI/CD systems configured in the repository
+	SchemaFiles  []string           `json:"schema_files,omitempty"`  // Database schema and model definition files
+	Migrations   []string           `json:"migrations,omitempty"`    // Directories of database migrations
+
+	// PrimaryEntryPoint is the entry point readers should start from, ranked
+	// first by manifests, main functions and the cmd/<name>/ convention
+	PrimaryEntryPoint string `json:"primary_entry_point,omitempty"`
+
+	// TestFrameworks are the test setups found in the repository, each with
+	// the command that likely runs its tests
+	TestFrameworks []TestFramework `json:"test_frameworks,omitempty"`
 }
 
 // Component represents a major component in the codebase
 type Component struct {
-	Name        string
-	Type        string // "API", "CLI", "Service", "Utility", etc.
-	Path        string
-	Description string
-	Files       []string
+	Name        string   `json:"name"`
+	Type        string   `json:"type"` // "API", "CLI", "Service", "Utility", etc.
+	Path        string   `json:"path"`
+	Description string   `json:"description"`
+	Files       []string `json:"files,omitempty"`
+}
+
+// CIPipeline is a CI/CD system configured in the repository
+type CIPipeline struct {
+	System string   `json:"system"` // e.g. "GitHub Actions"
+	Files  []string `json:"files"`  // Configuration files, relative to the repository root
+}
+
+// TestFramework is a test setup found in the repository
+type TestFramework struct {
+	Name    string `json:"name"`    // e.g. "Jest"
+	Command string `json:"command"` // Command that likely runs the tests, e.g. "npm test"
+}
+
+// Subproject is a separately built project within a monorepo
+type Subproject struct {
+	Name        string             `json:"name"`
+	Path        string             `json:"path"`     // Directory relative to the repository root
+	Manifest    string             `json:"manifest"` // Manifest file that defines it, e.g. package.json
+	Description string             `json:"description,omitempty"`
+	Languages   map[string]float64 `json:"languages,omitempty"`
+	Components  []Component        `json:"components,omitempty"`
+	EntryPoints []string           `json:"entry_points,omitempty"`
 }
 
 // AnalysisResult contains the complete analysis output
 type AnalysisResult struct {
-	RepoInfo      RepoInfo
-	Architecture  string
-	Setup         string
-	FlowDiagram   string
-	AnalyzedAt    time.Time
-	GeneratedWith string
+	RepoInfo      RepoInfo          `json:"repo_info"`
+	Architecture  string            `json:"architecture"`
+	Setup         string            `json:"setup"`
+	FlowDiagram   string            `json:"flow_diagram"`
+	DirStructure  string            `json:"dir_structure,omitempty"`  // Directory tree of the analyzed files, as shown to the model
+	Subprojects   []Subproject      `json:"subprojects,omitempty"`    // Set when the repository is a monorepo
+	ChunkAnalyses []string          `json:"chunk_analyses,omitempty"` // Per-chunk responses of a detailed analysis, when kept as an appendix
+	FileSummaries map[string]string `json:"file_summaries,omitempty"` // One-sentence summaries of significant files, keyed by path
+	CISummary     string            `json:"ci_summary,omitempty"`     // How the CI/CD configuration builds and deploys the project
+	DataModel     string            `json:"data_model,omitempty"`     // Entities and relationships defined by the schema and migrations
+	ReleaseTag    string            `json:"release_tag,omitempty"`    // Latest tag, when set with AnalyzeOptions.SinceTag
+	Changes       string            `json:"changes,omitempty"`        // Summary of the commits made since ReleaseTag
+	Incomplete    string            `json:"incomplete,omitempty"`     // Why the analysis stopped early, e.g. an exhausted token budget
+	Trimmed       []string          `json:"trimmed,omitempty"`        // Key files shortened or left out to fit the context
+	Revision      string            `json:"revision,omitempty"`       // Commit analyzed, when set with AnalyzeOptions.Rev
+	Scope         string            `json:"scope,omitempty"`          // Directory analyzed, when set with AnalyzeOptions.Subdir
+	AnalyzedAt    time.Time         `json:"analyzed_at"`
+	GeneratedWith string            `json:"generated_with"`
 }
 
 // Analyzer defines the interface for repository analysis
 type Analyzer interface {
-	// Analyze performs the complete repository analysis
-	Analyze(repoPath string, options AnalyzeOptions) (*AnalysisResult, error)
+	// Analyze performs the complete repository analysis. Canceling ctx, or
+	// its deadline passing, stops the requests to the model and fails it.
+	Analyze(ctx context.Context, repoPath string, options AnalyzeOptions) (*AnalysisResult, error)
+
+	// ExplainFile generates a detailed explanation of a specific file, stopping
+	// when ctx is done
+	ExplainFile(ctx context.Context, filePath string, options ExplainOptions) (string, error)
+
+	// ExplainDiff explains how a file changed since a base revision and why
+	// the change matters, stopping when ctx is done
+	ExplainDiff(ctx context.Context, filePath string, options ExplainDiffOptions) (string, error)
 
-	// ExplainFile generates a detailed explanation of a specific file
-	ExplainFile(filePath string, options ExplainOptions) (string, error)
+	// Components identifies the main components without generating full documentation
+	Components(repoPath string, options AnalyzeOptions) ([]Component, error)
+
+	// Changelog generates grouped release notes from recent commits
+	Changelog(repoPath string, options ChangelogOptions) (string, error)
+
+	// Chat reads a repository's files and starts a conversation about it
+	Chat(repoPath string, options ChatOptions) (*ChatSession, error)
+
+	// ListModels returns the models available from the configured endpoint
+	ListModels() ([]string, error)
 }
 
 // AnalyzeOptions contains configuration for the analysis
 type AnalyzeOptions struct {
-	ContextSize int
-	OpenAIKey   string
-	APIBase     string
-	Model       string
-	OutputPath  string
-	Detailed    bool // If true, perform detailed code analysis
+	ContextSize  int
+	OpenAIKey    string
+	APIBase      string
+	Model        string
+	OutputPath   string
+	Detailed     bool     // If true, perform detailed code analysis
+	SummaryOnly  bool     // If true, make a single request for a one-paragraph summary
+	ContextFiles []string // Files always included in the analysis input, relative to the repository
+	DocFiles     []string // Patterns of documentation files that seed the summary; nil uses DefaultDocFiles
+	Since        string   // If set, only analyze files changed since this commit or date
+	Rev          string   // If set, analyze the tree of this commit, tag or branch instead of the working tree
+	Subdir       string   // If set, only analyze files under this directory of the repository
+	Resume       bool     // If true, reuse chunk results saved by an interrupted detailed run
+	IgnoreDirs   []string // Additional directory names to skip when listing files
+	FileSource   string   // How to list files: "git" for tracked files (the default) or "worktree"
+	Untracked    bool     // If true, the "git" file source also lists untracked, non-ignored files
+	NoGit        bool     // If true, read a plain directory that need not be a git repository
+	NoRedact     bool     // If true, send file contents without redacting likely secrets
+	AllFiles     bool     // If true, detailed analysis reads every file, not just source files
+	DirDepth     int      // Directory levels shown in the prompt's tree; 0 shows all
+	ChunkSize    int      // Characters per chunk in detailed analysis; 0 derives it from ContextSize
+	ChunkOverlap int      // Characters of each chunk repeated at the start of the next
+	Language     string   // Natural language to write the analysis in, e.g. "Spanish"; empty for English
+	PromptSuffix string   // Extra instructions appended to every prompt, e.g. "Focus on security"
+	KeepChunks   string   // If set, write each chunk's prompt and response of a detailed analysis to this directory
+
+	ChunkAppendix    bool     // If true, include the per-chunk responses in the result as an appendix
+	FileSummaries    bool     // If true, detailed analysis also summarizes each significant file in one sentence
+	CISummary        bool     // If true, ask the model to summarize the detected CI/CD configuration
+	DataModel        bool     // If true, ask the model to summarize the detected schema and migrations
+	SinceTag         bool     // If true, also summarize the commits made since the most recent tag
+	GroupLanguages   bool     // If true, merge related languages in the statistics, e.g. React into JavaScript/TypeScript
+	AffectedSince    string   // If set, only analyze Go packages changed since this commit and the packages importing them
+	MaxContinuations int      // Follow-up requests allowed when a response is truncated
+	TokenBudget      int      // Tokens allowed across all requests of a run; 0 is unlimited
+	IncludeSensitive []string // Patterns removed from git.DefaultSensitiveFiles, e.g. "*.key"
+
+	// Concurrency bounds parallel work: file reads default to one per CPU and
+	// chunk analysis to llm.DefaultConcurrency when it is 0
+	Concurrency int
+
+	// ExtraBody is merged into every request body, replacing fields repo-sage
+	// sets itself, for backend-specific parameters such as seed
+	ExtraBody map[string]any
+
+	// RequestsPerMinute paces LLM requests to stay under the provider's rate
+	// limit; 0 is unlimited
+	RequestsPerMinute int
+
+	// StreamComponents, if set, receives each component Components returns
+	// as soon as it is known, instead of only once all have been identified
+	StreamComponents func(component Component)
 }
 
 // ExplainOptions contains configuration for file explanation
@@ -56,4 +171,55 @@ type ExplainOptions struct {
 	OpenAIKey   string
 	APIBase     string
 	Model       string
+
+	MaxContinuations int // Follow-up requests allowed when a response is truncated
+
+	Symbol   string // If set, explain only this function, type or method (Type.Method for Go)
+	Line     int    // If set, explain the declaration spanning this 1-based line
+	NoRedact bool   // If true, send the file without redacting likely secrets
+	Language string // Natural language to write the explanation in; empty for English
+	NoCache  bool   // If true, always query the model instead of reusing a cached explanation
+
+	PromptSuffix string // Extra instructions appended to the prompt
+
+	// Stream, if set, receives the explanation piece by piece as it is
+	// generated, or whole when it comes from the cache
+	Stream func(text string)
+}
+
+// ExplainDiffOptions contains configuration for explaining a file's changes
+type ExplainDiffOptions struct {
+	ContextSize int
+	Base        string // Commit, tag or branch to compare against, e.g. "main"
+	Head        string // Commit, tag or branch of the new version; empty for the working tree
+
+	NoRedact     bool   // If true, send both versions without redacting likely secrets
+	Language     string // Natural language to write the explanation in; empty for English
+	PromptSuffix string // Extra instructions appended to the prompt
+
+	// Stream, if set, receives the explanation piece by piece as it is generated
+	Stream func(text string)
+}
+
+// ChatOptions contains configuration for a conversation about a repository
+type ChatOptions struct {
+	ContextSize  int      // Context size in tokens; about half of it is used for retrieved files
+	IgnoreDirs   []string // Additional directory names to skip when listing files
+	FileSource   string   // How to list files: "git" for tracked files (the default) or "worktree"
+	Untracked    bool     // If true, the "git" file source also lists untracked, non-ignored files
+	NoGit        bool     // If true, read a plain directory that need not be a git repository
+	NoRedact     bool     // If true, send file contents without redacting likely secrets
+	Language     string   // Natural language to answer in; empty for English
+	PromptSuffix string   // Extra instructions appended to the system prompt
+
+	IncludeSensitive []string // Patterns removed from git.DefaultSensitiveFiles, e.g. "*.key"
+	Concurrency      int      // Parallel file reads; 0 uses one per CPU
+}
+
+// ChangelogOptions contains configuration for changelog generation
+type ChangelogOptions struct {
+	ContextSize int
+	Range       string // Revision range such as "v1.0.0..HEAD"
+	Count       int    // Number of recent commits to include
+	WithDiffs   bool   // If true, include truncated diffs alongside commit messages
 }
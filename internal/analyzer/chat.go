@@ -0,0 +1,223 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/priyupadhyay/repo-sage/pkg/llm"
+)
+
+// Limits on what each chat question sends to the model
+const (
+	maxChatFiles   = 8 // Files retrieved per question
+	maxChatHistory = 6 // Earlier turns resent with each question
+)
+
+// ChatSession is a conversation about one repository. Its files are read once
+// when the session starts; each question retrieves the most relevant of them.
+type ChatSession struct {
+	Name string // Repository name
+
+	client   llm.Client
+	overview string
+	files    map[string]string
+	history  []llm.ChatTurn
+	options  ChatOptions
+}
+
+// ChatAnswer is the answer to one question and the files it was based on
+type ChatAnswer struct {
+	Answer  string
+	Sources []string
+}
+
+func (a *analyzer) Chat(repoPath string, options ChatOptions) (*ChatSession, error) {
+	repo, err := openRepository(repoPath, options.NoGit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	repo.IgnoreDirs(options.IgnoreDirs...)
+	repo.IncludeSensitive(options.IncludeSensitive...)
+	if err := setFileSource(repo, options.FileSource, options.Untracked); err != nil {
+		return nil, err
+	}
+
+	slog.Info("📂 Scanning repository files...")
+	files, err := repo.ListFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repository files: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no analyzable files found in %s", repo.Path)
+	}
+
+	languages, err := repoLanguages(repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get language statistics: %w", err)
+	}
+
+	slog.Info("📖 Reading files...")
+	contents, err := readFiles(repo, sourceFiles(files, nil), options.Concurrency)
+	if err != nil {
+		return nil, err
+	}
+	if !options.NoRedact {
+		redactFiles(contents)
+	}
+	slog.Info(fmt.Sprintf("Indexed %d files", len(contents)))
+
+	name := filepath.Base(repo.Path)
+	overview := fmt.Sprintf("Name: %s\nLanguages: %s\nEntry points: %s\n\nDirectory Structure:\n%s",
+		name, formatLanguages(languages), strings.Join(findEntryPoints(files, nil), ", "), buildDirStructure(files, 3))
+
+	return &ChatSession{
+		Name:     name,
+		client:   a.llmClient,
+		overview: overview,
+		files:    contents,
+		options:  options,
+	}, nil
+}
+
+// Ask answers a question, retrieving the files that best match it and
+// resending the most recent turns of the conversation
+func (s *ChatSession) Ask(question string) (*ChatAnswer, error) {
+	sources := relevantFiles(s.files, question, chatContextChars(s.options.ContextSize))
+	files := make(map[string]string, len(sources))
+	for _, name := range sources {
+		files[name] = truncateChars(s.files[name], chatContextChars(s.options.ContextSize))
+	}
+	slog.Debug("retrieved files for question", "files", sources)
+
+	history := s.history
+	if len(history) > maxChatHistory {
+		history = history[len(history)-maxChatHistory:]
+	}
+
+	output, err := s.client.Chat(context.Background(), llm.ChatInput{
+		Question:     question,
+		Overview:     s.overview,
+		Files:        files,
+		History:      history,
+		Language:     s.options.Language,
+		PromptSuffix: s.options.PromptSuffix,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to answer question: %w", err)
+	}
+
+	s.history = append(s.history, llm.ChatTurn{Question: question, Answer: output.Answer})
+	return &ChatAnswer{Answer: output.Answer, Sources: sources}, nil
+}
+
+// chatContextChars is the number of characters of file content sent with each
+// question: about half of a context of contextSize tokens
+func chatContextChars(contextSize int) int {
+	if contextSize <= 0 {
+		contextSize = 4000
+	}
+	return contextSize * 2
+}
+
+// truncateChars cuts content to at most limit bytes without splitting a UTF-8 character
+func truncateChars(content string, limit int) string {
+	if len(content) <= limit {
+		return content
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	return content[:cut] + "\n... (truncated)"
+}
+
+var wordPattern = regexp.MustCompile(`[a-z0-9_]+`)
+
+// questionStopWords are common words that say nothing about which files matter
+var questionStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "was": true, "how": true,
+	"what": true, "where": true, "when": true, "which": true, "who": true, "why": true,
+	"does": true, "this": true, "that": true, "with": true, "from": true, "into": true,
+	"can": true, "there": true, "code": true, "file": true, "files": true, "use": true,
+	"used": true, "handled": true, "work": true, "works": true, "implemented": true,
+}
+
+// questionTerms extracts the search terms of a question, trimming common
+// suffixes so "handlers" also matches "handler"
+func questionTerms(question string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, word := range wordPattern.FindAllString(strings.ToLower(question), -1) {
+		if len(word) < 3 || questionStopWords[word] {
+			continue
+		}
+		if len(word) > 5 {
+			for _, suffix := range []string{"ing", "ed", "es", "s"} {
+				if strings.HasSuffix(word, suffix) {
+					word = strings.TrimSuffix(word, suffix)
+					break
+				}
+			}
+		}
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// relevantFiles ranks files by how often the question's terms appear in their
+// paths and contents, and returns the best matches that fit in budget
+// characters. Matches in the path count more than matches in the content.
+func relevantFiles(files map[string]string, question string, budget int) []string {
+	terms := questionTerms(question)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		name  string
+		score int
+	}
+	var ranked []scored
+	for name, content := range files {
+		lowerName, lowerContent := strings.ToLower(name), strings.ToLower(content)
+		score := 0
+		for _, term := range terms {
+			score += 10 * strings.Count(lowerName, term)
+			score += min(strings.Count(lowerContent, term), 20)
+		}
+		if score > 0 {
+			ranked = append(ranked, scored{name, score})
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].name < ranked[j].name
+	})
+
+	// The best match is always sent, truncated if needed; others only when they fit
+	var selected []string
+	used := 0
+	for _, r := range ranked {
+		if len(selected) == maxChatFiles {
+			break
+		}
+		size := len(files[r.name])
+		if len(selected) > 0 && used+size > budget {
+			continue
+		}
+		selected = append(selected, r.name)
+		used += size
+	}
+	return selected
+}
@@ -0,0 +1,106 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileCheckpoint stores chunk analyses in a JSON file keyed by the hash of the
+// llm.Checkpoint key, so a retried run only repeats chunks that did not complete
+type fileCheckpoint struct {
+	mu        sync.Mutex
+	path      string
+	responses map[string]string
+}
+
+// checkpointPath returns the checkpoint file for a detailed run of a repository
+func checkpointPath(repoPath, model string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get cache directory: %w", err)
+	}
+	sum := sha256.Sum256([]byte(repoPath + "\x00" + model))
+	return filepath.Join(cacheDir, "repo-sage", "runs", hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// openCheckpoint loads an existing checkpoint, or starts an empty one
+func openCheckpoint(path string) (*fileCheckpoint, error) {
+	cp := &fileCheckpoint{
+		path:      path,
+		responses: make(map[string]string),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cp, nil
+		}
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &cp.responses); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+
+	return cp, nil
+}
+
+// Len returns the number of stored chunk analyses
+func (c *fileCheckpoint) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.responses)
+}
+
+// Load returns the stored response for a key, if any
+func (c *fileCheckpoint) Load(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	response, ok := c.responses[chunkKey(key)]
+	return response, ok
+}
+
+// Save stores the response for a key and writes the checkpoint to disk
+func (c *fileCheckpoint) Save(key string, response string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses[chunkKey(key)] = response
+
+	data, err := json.Marshal(c.responses)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	// Write to a temporary file first so an interrupted write can't corrupt the checkpoint
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// Remove deletes the checkpoint once the run has completed
+func (c *fileCheckpoint) Remove() error {
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	return nil
+}
+
+func chunkKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,46 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/priyupadhyay/repo-sage/pkg/llm"
+)
+
+// writeChunks writes the prompt and response of each chunk of a detailed
+// analysis to its own Markdown file in dir, named chunk-001.md and so on
+func writeChunks(dir string, chunks []llm.ChunkAnalysis) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create chunk directory: %w", err)
+	}
+
+	for i, chunk := range chunks {
+		// Use a fence longer than any backtick run in the prompt so code blocks inside it stay intact
+		fence := "```"
+		for strings.Contains(chunk.Prompt, fence) {
+			fence += "`"
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "# Chunk %d of %d\n\n", i+1, len(chunks))
+		fmt.Fprintf(&b, "## Prompt\n\n%s\n%s\n%s\n\n", fence, chunk.Prompt, fence)
+		fmt.Fprintf(&b, "## Response\n\n%s\n", chunk.Response)
+
+		path := filepath.Join(dir, fmt.Sprintf("chunk-%03d.md", i+1))
+		if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write chunk analysis: %w", err)
+		}
+	}
+	return nil
+}
+
+// chunkResponses returns the responses of the chunks in order
+func chunkResponses(chunks []llm.ChunkAnalysis) []string {
+	responses := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		responses[i] = chunk.Response
+	}
+	return responses
+}
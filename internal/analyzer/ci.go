@@ -0,0 +1,82 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/priyupadhyay/repo-sage/pkg/git"
+	"github.com/priyupadhyay/repo-sage/pkg/llm"
+)
+
+// ciSystems recognize the configuration files of CI/CD systems by their
+// repository-relative paths, in the order they are reported
+var ciSystems = []struct {
+	name  string
+	match func(file string) bool
+}{
+	{"GitHub Actions", func(file string) bool {
+		ext := path.Ext(file)
+		return path.Dir(file) == ".github/workflows" && (ext == ".yml" || ext == ".yaml")
+	}},
+	{"GitLab CI", func(file string) bool { return file == ".gitlab-ci.yml" }},
+	{"Jenkins", func(file string) bool { return path.Base(file) == "Jenkinsfile" }},
+	{"CircleCI", func(file string) bool { return file == ".circleci/config.yml" }},
+	{"Azure Pipelines", func(file string) bool { return file == "azure-pipelines.yml" || file == "azure-pipelines.yaml" }},
+	{"Travis CI", func(file string) bool { return file == ".travis.yml" }},
+	{"Bitbucket Pipelines", func(file string) bool { return file == "bitbucket-pipelines.yml" }},
+	{"Drone", func(file string) bool { return file == ".drone.yml" }},
+}
+
+// detectCI finds the CI/CD systems configured in the repository and their files
+func detectCI(files []string) []CIPipeline {
+	var pipelines []CIPipeline
+	for _, system := range ciSystems {
+		var matched []string
+		for _, file := range files {
+			if system.match(file) {
+				matched = append(matched, file)
+			}
+		}
+		if len(matched) > 0 {
+			pipelines = append(pipelines, CIPipeline{System: system.name, Files: matched})
+		}
+	}
+	return pipelines
+}
+
+// summarizeCI asks the model how the pipelines build and deploy the project.
+// The summary is optional, so failures are logged and leave it empty.
+func (a *analyzer) summarizeCI(ctx context.Context, repo *git.Repository, pipelines []CIPipeline, options AnalyzeOptions) string {
+	files := make(map[string]string)
+	for _, pipeline := range pipelines {
+		for _, file := range pipeline.Files {
+			content, err := repo.ReadFile(file)
+			if err != nil {
+				slog.Warn("failed to read CI configuration", "file", file, "error", err)
+				continue
+			}
+			files[file] = string(content)
+		}
+	}
+	if len(files) == 0 {
+		return ""
+	}
+	if !options.NoRedact {
+		redactFiles(files)
+	}
+
+	slog.Info(fmt.Sprintf("⚙️  Summarizing %d CI/CD configuration files...", len(files)))
+	output, err := a.llmClient.SummarizeCI(ctx, llm.CIInput{
+		Files:        files,
+		Language:     options.Language,
+		PromptSuffix: options.PromptSuffix,
+	})
+	if err != nil {
+		slog.Warn("failed to summarize the CI/CD configuration", "error", err)
+		return ""
+	}
+	return strings.TrimSpace(output.Summary)
+}
@@ -0,0 +1,133 @@
+package analyzer
+
+import (
+	"math"
+	"sort"
+)
+
+// minLanguageShift is the smallest change in a language's share, in percentage
+// points, reported by CompareResults; smaller ones are noise from edits
+const minLanguageShift = 0.5
+
+// ResultDiff describes how a repository changed between two analyses
+type ResultDiff struct {
+	AddedComponents    []Component
+	RemovedComponents  []Component
+	MovedComponents    []ComponentMove
+	Languages          []LanguageShift
+	Dependencies       []DependencyChange
+	AddedEntryPoints   []string
+	RemovedEntryPoints []string
+	OldLicense         string
+	NewLicense         string
+}
+
+// ComponentMove is a component found in both analyses under a different path or type
+type ComponentMove struct {
+	Old Component
+	New Component
+}
+
+// LanguageShift is a change in a language's share of the code, in percent.
+// Old is 0 for a new language and New is 0 for a removed one.
+type LanguageShift struct {
+	Language string
+	Old      float64
+	New      float64
+}
+
+// DependencyChange is an added, removed or upgraded dependency. Old is empty
+// for an added dependency and New is empty for a removed one.
+type DependencyChange struct {
+	Name string
+	Old  string
+	New  string
+}
+
+// Empty reports whether the analyses show no differences
+func (d *ResultDiff) Empty() bool {
+	return len(d.AddedComponents) == 0 && len(d.RemovedComponents) == 0 && len(d.MovedComponents) == 0 &&
+		len(d.Languages) == 0 && len(d.Dependencies) == 0 &&
+		len(d.AddedEntryPoints) == 0 && len(d.RemovedEntryPoints) == 0 && d.OldLicense == d.NewLicense
+}
+
+// CompareResults compares two analysis results of a repository, such as those
+// saved for two releases. Components are matched by name, so the model naming
+// a component differently between runs shows it as removed and added.
+func CompareResults(before, after *AnalysisResult) *ResultDiff {
+	diff := &ResultDiff{
+		OldLicense: before.RepoInfo.License,
+		NewLicense: after.RepoInfo.License,
+	}
+
+	oldComponents := make(map[string]Component)
+	for _, c := range before.RepoInfo.Components {
+		oldComponents[c.Name] = c
+	}
+	newComponents := make(map[string]Component)
+	for _, c := range after.RepoInfo.Components {
+		newComponents[c.Name] = c
+		o, ok := oldComponents[c.Name]
+		switch {
+		case !ok:
+			diff.AddedComponents = append(diff.AddedComponents, c)
+		case o.Path != c.Path || o.Type != c.Type:
+			diff.MovedComponents = append(diff.MovedComponents, ComponentMove{Old: o, New: c})
+		}
+	}
+	for _, c := range before.RepoInfo.Components {
+		if _, ok := newComponents[c.Name]; !ok {
+			diff.RemovedComponents = append(diff.RemovedComponents, c)
+		}
+	}
+
+	for _, language := range unionKeys(before.RepoInfo.Languages, after.RepoInfo.Languages) {
+		o, n := before.RepoInfo.Languages[language], after.RepoInfo.Languages[language]
+		if math.Abs(n-o) >= minLanguageShift || (o == 0) != (n == 0) {
+			diff.Languages = append(diff.Languages, LanguageShift{Language: language, Old: o, New: n})
+		}
+	}
+
+	for _, name := range unionKeys(before.RepoInfo.Dependencies, after.RepoInfo.Dependencies) {
+		o, oldOK := before.RepoInfo.Dependencies[name]
+		n, newOK := after.RepoInfo.Dependencies[name]
+		if oldOK != newOK || o != n {
+			diff.Dependencies = append(diff.Dependencies, DependencyChange{Name: name, Old: o, New: n})
+		}
+	}
+
+	diff.AddedEntryPoints = missingFrom(after.RepoInfo.EntryPoints, before.RepoInfo.EntryPoints)
+	diff.RemovedEntryPoints = missingFrom(before.RepoInfo.EntryPoints, after.RepoInfo.EntryPoints)
+	return diff
+}
+
+// unionKeys returns the keys of both maps in sorted order
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var keys []string
+	for _, m := range []map[string]V{a, b} {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// missingFrom returns the values of a that are not in b, in the order of a
+func missingFrom(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, value := range b {
+		inB[value] = true
+	}
+	var missing []string
+	for _, value := range a {
+		if !inB[value] {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/priyupadhyay/repo-sage/pkg/git"
+	"github.com/priyupadhyay/repo-sage/pkg/llm"
+)
+
+// maxDataModelFiles bounds the files sent for the data model summary: every
+// schema file first, then the newest migrations
+const maxDataModelFiles = 20
+
+// schemaFileNames are file names that define a data model wherever they appear
+var schemaFileNames = map[string]bool{
+	"schema.prisma": true,
+	"schema.rb":     true, // Rails
+	"models.py":     true, // Django
+}
+
+// detectDataModel finds database schema files and migration directories.
+// Migrations live in a "migrations" directory or Rails' db/migrate; schema
+// files are SQL and Prisma files and well-known model definitions elsewhere.
+func detectDataModel(files []string) (schemaFiles, migrations []string) {
+	seen := make(map[string]bool)
+	for _, file := range files {
+		if dir := migrationDir(file); dir != "" {
+			if !seen[dir] {
+				seen[dir] = true
+				migrations = append(migrations, dir)
+			}
+			continue
+		}
+		ext := strings.ToLower(path.Ext(file))
+		if ext == ".sql" || ext == ".prisma" || schemaFileNames[path.Base(file)] {
+			schemaFiles = append(schemaFiles, file)
+		}
+	}
+	sort.Strings(schemaFiles)
+	sort.Strings(migrations)
+	return schemaFiles, migrations
+}
+
+// migrationDir returns the outermost migration directory containing the file,
+// or an empty string if it is not a migration
+func migrationDir(file string) string {
+	parts := strings.Split(path.Dir(file), "/")
+	for i, part := range parts {
+		if part == "migrations" || (part == "migrate" && i > 0 && parts[i-1] == "db") {
+			return strings.Join(parts[:i+1], "/")
+		}
+	}
+	return ""
+}
+
+// summarizeDataModel asks the model to describe the entities and relationships
+// defined by the schema files and the newest migrations. The summary is
+// optional, so failures are logged and leave it empty.
+func (a *analyzer) summarizeDataModel(ctx context.Context, repo *git.Repository, files, schemaFiles []string, options AnalyzeOptions) string {
+	selected := schemaFiles
+	if len(selected) > maxDataModelFiles {
+		selected = selected[:maxDataModelFiles]
+	}
+	var migrationFiles []string
+	for _, file := range files {
+		if migrationDir(file) != "" && path.Base(file) != "__init__.py" {
+			migrationFiles = append(migrationFiles, file)
+		}
+	}
+	// Migrations are usually named by timestamp or sequence, so the last ones are the newest
+	sort.Strings(migrationFiles)
+	if room := maxDataModelFiles - len(selected); len(migrationFiles) > room {
+		migrationFiles = migrationFiles[len(migrationFiles)-room:]
+	}
+	selected = append(append([]string{}, selected...), migrationFiles...)
+
+	contents := make(map[string]string)
+	for _, file := range selected {
+		content, err := repo.ReadFile(file)
+		if err != nil {
+			slog.Warn("failed to read schema file", "file", file, "error", err)
+			continue
+		}
+		contents[file] = string(content)
+	}
+	if len(contents) == 0 {
+		return ""
+	}
+	if !options.NoRedact {
+		redactFiles(contents)
+	}
+
+	slog.Info(fmt.Sprintf("🗄  Summarizing the data model from %d schema and migration files...", len(contents)))
+	output, err := a.llmClient.SummarizeDataModel(ctx, llm.DataModelInput{
+		Files:        contents,
+		Language:     options.Language,
+		PromptSuffix: options.PromptSuffix,
+	})
+	if err != nil {
+		slog.Warn("failed to summarize the data model", "error", err)
+		return ""
+	}
+	return strings.TrimSpace(output.Summary)
+}
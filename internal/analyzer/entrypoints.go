@@ -0,0 +1,157 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"go/ast"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/priyupadhyay/repo-sage/pkg/git"
+)
+
+// entryPointNames are file names that usually start a program
+var entryPointNames = map[string]bool{
+	"main.go":     true,
+	"main.rs":     true,
+	"index.js":    true,
+	"app.py":      true,
+	"__main__.py": true,
+}
+
+// secondaryDirs hold programs that support the project rather than run it,
+// such as examples and build tools, so their entry points rank last
+var secondaryDirs = map[string]bool{
+	"example": true, "examples": true, "test": true, "tests": true, "testdata": true,
+	"scripts": true, "tools": true, "hack": true,
+}
+
+// findEntryPoints identifies potential entry points in the repository, most
+// likely first. Examples, tests and tools rank last. Otherwise known entry
+// points, declared by a manifest or found by parsing the code, rank above
+// files that only have an entry point's name, and within each group cmd/<name>/
+// programs come first, then those at the root or in src/, then the others.
+func findEntryPoints(files []string, known []string) []string {
+	isKnown := make(map[string]bool, len(known))
+	for _, file := range known {
+		isKnown[file] = true
+	}
+
+	var entryPoints []string
+	for _, file := range files {
+		if isKnown[file] || entryPointNames[path.Base(file)] {
+			entryPoints = append(entryPoints, file)
+		}
+	}
+
+	sort.SliceStable(entryPoints, func(i, j int) bool {
+		a, b := entryPoints[i], entryPoints[j]
+		ra, rb := entryPointRank(a), entryPointRank(b)
+		if (ra == secondaryRank) != (rb == secondaryRank) {
+			return rb == secondaryRank
+		}
+		if isKnown[a] != isKnown[b] {
+			return isKnown[a]
+		}
+		if ra != rb {
+			return ra < rb
+		}
+		if da, db := strings.Count(a, "/"), strings.Count(b, "/"); da != db {
+			return da < db
+		}
+		return a < b
+	})
+	return entryPoints
+}
+
+// secondaryRank is the rank of entry points in examples, tests and tools
+const secondaryRank = 3
+
+// entryPointRank orders entry points by where they live: 0 for cmd/<name>/,
+// 1 for the root and src/, 2 for other directories and secondaryRank for
+// examples, tests and tools
+func entryPointRank(file string) int {
+	dirs := strings.Split(path.Dir(file), "/")
+	for _, dir := range dirs {
+		if secondaryDirs[dir] {
+			return secondaryRank
+		}
+	}
+	switch {
+	case len(dirs) == 2 && dirs[0] == "cmd":
+		return 0
+	case dirs[0] == "." || (len(dirs) == 1 && dirs[0] == "src"):
+		return 1
+	}
+	return 2
+}
+
+// goMainFiles returns the Go files that declare the main function of a main package
+func goMainFiles(packages []*goPackage) []string {
+	var mains []string
+	for _, pkg := range packages {
+		if pkg.Name != "main" {
+			continue
+		}
+		for file, syntax := range pkg.Files {
+			for _, decl := range syntax.Decls {
+				if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "main" {
+					mains = append(mains, file)
+				}
+			}
+		}
+	}
+	return mains
+}
+
+// cargoBinPathRe matches the path of a [[bin]] target in Cargo.toml
+var cargoBinPathRe = regexp.MustCompile(`(?m)^\s*path\s*=\s*"([^"]+)"`)
+
+// manifestEntryPoints returns the entry points declared by the package.json
+// and Cargo.toml at the root of the analyzed directory: package.json's bin and
+// main fields, and Cargo's default src/main.rs and [[bin]] target paths
+func manifestEntryPoints(repo *git.Repository) []string {
+	root := repo.Scope()
+	var declared []string
+
+	if content, err := repo.ReadFile(path.Join(root, "package.json")); err == nil {
+		var manifest struct {
+			Main string          `json:"main"`
+			Bin  json.RawMessage `json:"bin"`
+		}
+		if json.Unmarshal(content, &manifest) == nil {
+			// bin is either a single path or a map of command names to paths
+			var bin string
+			var bins map[string]string
+			if json.Unmarshal(manifest.Bin, &bin) == nil {
+				declared = append(declared, bin)
+			} else if json.Unmarshal(manifest.Bin, &bins) == nil {
+				for _, file := range bins {
+					declared = append(declared, file)
+				}
+			}
+			declared = append(declared, manifest.Main)
+		}
+	}
+
+	if content, err := repo.ReadFile(path.Join(root, "Cargo.toml")); err == nil {
+		declared = append(declared, "src/main.rs")
+		for _, section := range strings.Split(string(content), "[[bin]]")[1:] {
+			if end := strings.Index(section, "\n["); end != -1 {
+				section = section[:end]
+			}
+			if m := cargoBinPathRe.FindStringSubmatch(section); m != nil {
+				declared = append(declared, m[1])
+			}
+		}
+	}
+
+	var entryPoints []string
+	for _, file := range declared {
+		if file != "" {
+			entryPoints = append(entryPoints, path.Join(root, path.Clean(file)))
+		}
+	}
+	return entryPoints
+}
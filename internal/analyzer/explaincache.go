@@ -0,0 +1,47 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/priyupadhyay/repo-sage/pkg/llm"
+)
+
+// explanationCachePath returns the cache file for an explanation, keyed by a hash
+// of the model and everything sent in the request, so editing the file, changing
+// the symbol or switching models produces a fresh explanation
+func explanationCachePath(model string, input llm.ExplainInput) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get cache directory: %w", err)
+	}
+	key := strings.Join([]string{model, input.Language, input.PromptSuffix, input.Filename, input.Content}, "\x00")
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(cacheDir, "repo-sage", "explanations", hex.EncodeToString(sum[:16])+".md"), nil
+}
+
+// loadExplanation returns a cached explanation, if any
+func loadExplanation(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// saveExplanation caches an explanation. Failures only cost the speed-up, so
+// they are logged rather than returned.
+func saveExplanation(path, explanation string) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = os.WriteFile(path, []byte(explanation), 0644)
+	}
+	if err != nil {
+		slog.Debug("failed to cache explanation", "error", err)
+	}
+}
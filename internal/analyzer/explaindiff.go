@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/priyupadhyay/repo-sage/pkg/llm"
+)
+
+func (a *analyzer) ExplainDiff(ctx context.Context, filePath string, options ExplainDiffOptions) (string, error) {
+	repo, absPath, relPath, err := findRepository(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	oldContent, existed, err := repo.LookupFileAtRev(options.Base, relPath)
+	if err != nil {
+		return "", err
+	}
+	if !existed {
+		slog.Info(fmt.Sprintf("%s does not exist at %s; explaining it as a new file", relPath, options.Base))
+	}
+
+	head := "working tree"
+	var newContent []byte
+	if options.Head != "" {
+		head = options.Head
+		var exists bool
+		newContent, exists, err = repo.LookupFileAtRev(options.Head, relPath)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return "", fmt.Errorf("%s does not exist at %s", relPath, options.Head)
+		}
+	} else if newContent, err = repo.ReadFile(relPath); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if string(oldContent) == string(newContent) {
+		return "", fmt.Errorf("%s is unchanged between %s and %s", relPath, options.Base, describeHead(options.Head))
+	}
+	oldSource, ok := toText(relPath, oldContent)
+	if !ok {
+		return "", fmt.Errorf("%s appears to be a binary file at %s", relPath, options.Base)
+	}
+	newSource, ok := toText(relPath, newContent)
+	if !ok {
+		return "", fmt.Errorf("%s appears to be a binary file", relPath)
+	}
+	if !options.NoRedact {
+		oldSource, _ = redactSecrets(absPath, oldSource)
+		newSource, _ = redactSecrets(absPath, newSource)
+	}
+
+	slog.Info(fmt.Sprintf("🔀 Explaining the changes to %s since %s...", relPath, options.Base))
+	explanation, err := a.llmClient.ExplainDiff(ctx, llm.ExplainDiffInput{
+		Filename:    relPath,
+		Base:        options.Base,
+		Head:        head,
+		Old:         oldSource,
+		New:         newSource,
+		ContextSize: options.ContextSize,
+		Language:    options.Language,
+
+		PromptSuffix: options.PromptSuffix,
+	}, options.Stream)
+	if err != nil {
+		return "", fmt.Errorf("failed to explain the changes: %w", err)
+	}
+	return explanation.Explanation, nil
+}
+
+// describeHead names the new version of an explained diff for messages
+func describeHead(head string) string {
+	if head == "" {
+		return "the working tree"
+	}
+	return head
+}
@@ -0,0 +1,67 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Finding is a documentation quality problem detected in an analysis result,
+// reported to CI systems through formats such as SARIF
+type Finding struct {
+	RuleID  string `json:"rule_id"`
+	Level   string `json:"level"` // "error", "warning" or "note"
+	Message string `json:"message"`
+	Path    string `json:"path,omitempty"` // Repository-relative file or directory; empty for repository-level findings
+}
+
+// Rule describes a kind of finding
+type Rule struct {
+	ID          string
+	Description string
+}
+
+// Rules lists every rule that Findings can report
+var Rules = []Rule{
+	{ID: "missing-description", Description: "The repository has no description of what it does"},
+	{ID: "missing-architecture", Description: "The repository has no architecture overview"},
+	{ID: "missing-setup", Description: "No setup instructions could be found or inferred"},
+	{ID: "undocumented-component", Description: "A component has no description"},
+}
+
+// Findings checks an analysis result for documentation gaps
+func Findings(result *AnalysisResult) []Finding {
+	var findings []Finding
+	if strings.TrimSpace(result.RepoInfo.Description) == "" {
+		findings = append(findings, Finding{
+			RuleID:  "missing-description",
+			Level:   "warning",
+			Message: "The repository has no description; add a README explaining what the project does.",
+		})
+	}
+	if strings.TrimSpace(result.Architecture) == "" {
+		findings = append(findings, Finding{
+			RuleID:  "missing-architecture",
+			Level:   "note",
+			Message: "No architecture overview could be produced for the repository.",
+		})
+	}
+	if strings.TrimSpace(result.Setup) == "" {
+		findings = append(findings, Finding{
+			RuleID:  "missing-setup",
+			Level:   "warning",
+			Message: "No setup instructions were found; document how to build and run the project.",
+		})
+	}
+	for _, c := range result.RepoInfo.Components {
+		if strings.TrimSpace(c.Description) != "" {
+			continue
+		}
+		findings = append(findings, Finding{
+			RuleID:  "undocumented-component",
+			Level:   "note",
+			Message: fmt.Sprintf("%s %s has no description.", c.Type, c.Name),
+			Path:    c.Path,
+		})
+	}
+	return findings
+}
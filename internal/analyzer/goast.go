@@ -0,0 +1,331 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/priyupadhyay/repo-sage/pkg/git"
+)
+
+// goPackage is a Go package parsed from the repository, keyed by directory
+type goPackage struct {
+	Dir   string // Directory relative to the repository root
+	Name  string
+	Doc   string
+	Files map[string]*ast.File // File path -> syntax tree
+}
+
+// parseGoPackages parses the non-test Go files among files, grouped by directory.
+// Files that fail to parse are skipped so one broken file doesn't hide a package.
+func parseGoPackages(repo *git.Repository, files []string) []*goPackage {
+	fset := token.NewFileSet()
+	byDir := make(map[string]*goPackage)
+
+	for _, file := range files {
+		if filepath.Ext(file) != ".go" || strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		content, err := repo.ReadFile(file)
+		if err != nil {
+			slog.Debug("skipping Go file", "path", file, "error", err)
+			continue
+		}
+		parsed, err := parser.ParseFile(fset, file, content, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			slog.Debug("skipping Go file", "path", file, "error", err)
+			continue
+		}
+
+		dir := path.Dir(file)
+		pkg, ok := byDir[dir]
+		if !ok {
+			pkg = &goPackage{Dir: dir, Name: parsed.Name.Name, Files: make(map[string]*ast.File)}
+			byDir[dir] = pkg
+		}
+		if pkg.Doc == "" && parsed.Doc != nil {
+			pkg.Doc = parsed.Doc.Text()
+		}
+		pkg.Files[file] = parsed
+	}
+
+	packages := make([]*goPackage, 0, len(byDir))
+	for _, pkg := range byDir {
+		packages = append(packages, pkg)
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].Dir < packages[j].Dir
+	})
+	return packages
+}
+
+// goComponents lists Go packages with their exported types and functions. Paths
+// and kinds come from the syntax tree; descriptions come from doc comments and
+// are left empty for the model to fill in when a declaration is undocumented.
+func goComponents(packages []*goPackage) []Component {
+	var components []Component
+	for _, pkg := range packages {
+		kind := "package"
+		if pkg.Name == "main" {
+			kind = "command"
+		}
+		name := pkg.Dir
+		if name == "." {
+			name = pkg.Name
+		}
+		components = append(components, Component{
+			Name:        name,
+			Type:        kind,
+			Path:        pkg.Dir,
+			Description: firstSentence(pkg.Doc),
+		})
+
+		fileNames := make([]string, 0, len(pkg.Files))
+		for file := range pkg.Files {
+			fileNames = append(fileNames, file)
+		}
+		sort.Strings(fileNames)
+
+		for _, file := range fileNames {
+			components = append(components, exportedDecls(pkg.Name, file, pkg.Files[file])...)
+		}
+	}
+	return components
+}
+
+// exportedDecls returns the exported types and top-level functions declared in a file
+func exportedDecls(pkgName, file string, parsed *ast.File) []Component {
+	var components []Component
+	for _, decl := range parsed.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv != nil || !d.Name.IsExported() {
+				continue
+			}
+			components = append(components, Component{
+				Name:        pkgName + "." + d.Name.Name,
+				Type:        "func",
+				Path:        file,
+				Description: firstSentence(d.Doc.Text()),
+			})
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if !ts.Name.IsExported() {
+					continue
+				}
+				doc := ts.Doc
+				if doc == nil && len(d.Specs) == 1 {
+					doc = d.Doc
+				}
+				components = append(components, Component{
+					Name:        pkgName + "." + ts.Name.Name,
+					Type:        typeKind(ts.Type),
+					Path:        file,
+					Description: firstSentence(doc.Text()),
+				})
+			}
+		}
+	}
+	return components
+}
+
+// typeKind names the kind of a type declaration
+func typeKind(expr ast.Expr) string {
+	switch expr.(type) {
+	case *ast.InterfaceType:
+		return "interface"
+	case *ast.StructType:
+		return "struct"
+	case *ast.FuncType:
+		return "func type"
+	}
+	return "type"
+}
+
+// firstSentence returns the first sentence of a doc comment on a single line
+func firstSentence(doc string) string {
+	text := strings.Join(strings.Fields(doc), " ")
+	if i := strings.Index(text, ". "); i != -1 {
+		return text[:i+1]
+	}
+	return text
+}
+
+// mergeComponents keeps the statically detected components and fills in missing
+// descriptions from the model's components, matched by name or path. Without
+// any static components the model's list is used as-is.
+func mergeComponents(static, described []Component) []Component {
+	if len(static) == 0 {
+		return described
+	}
+
+	byName := make(map[string]string)
+	byPath := make(map[string]string)
+	for _, c := range described {
+		if c.Description == "" {
+			continue
+		}
+		byName[strings.ToLower(c.Name)] = c.Description
+		byPath[strings.TrimSuffix(filepath.ToSlash(c.Path), "/")] = c.Description
+	}
+
+	merged := make([]Component, len(static))
+	for i, c := range static {
+		if c.Description == "" {
+			if desc, ok := byName[strings.ToLower(c.Name)]; ok {
+				c.Description = desc
+			} else if desc, ok := byPath[c.Path]; ok && (c.Type == "package" || c.Type == "command") {
+				c.Description = desc
+			}
+		}
+		merged[i] = c
+	}
+	return merged
+}
+
+// goModules maps the directory of each go.mod among files to its module path
+func goModules(repo *git.Repository, files []string) map[string]string {
+	modules := make(map[string]string)
+	for _, file := range files {
+		if filepath.Base(file) != "go.mod" {
+			continue
+		}
+		content, err := repo.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[0] == "module" {
+				modules[path.Dir(file)] = strings.Trim(fields[1], `"`)
+				break
+			}
+		}
+	}
+	return modules
+}
+
+// withEnclosingModules adds the go.mod paths of the directories enclosing a
+// scope to files, so packages of a scoped analysis still resolve their module
+func withEnclosingModules(scope string, files []string) []string {
+	if scope == "" {
+		return files
+	}
+	withModules := append([]string(nil), files...)
+	for dir := scope; dir != "."; {
+		dir = path.Dir(dir)
+		withModules = append(withModules, path.Join(dir, "go.mod"))
+	}
+	return withModules
+}
+
+// importPath returns the import path of a package directory using the closest enclosing module
+func importPath(dir string, modules map[string]string) (string, bool) {
+	for moduleDir := dir; ; moduleDir = path.Dir(moduleDir) {
+		if module, ok := modules[moduleDir]; ok {
+			if dir == moduleDir {
+				return module, true
+			}
+			rel := strings.TrimPrefix(dir, moduleDir+"/")
+			if moduleDir == "." {
+				rel = dir
+			}
+			return module + "/" + rel, true
+		}
+		if moduleDir == "." || moduleDir == "/" {
+			return "", false
+		}
+	}
+}
+
+// goImportGraph renders the imports between the repository's own Go packages as
+// a Mermaid graph. It returns an empty string when no package imports another.
+func goImportGraph(packages []*goPackage, modules map[string]string) string {
+	edges := goImportEdges(packages, modules)
+	if len(edges) == 0 {
+		return ""
+	}
+
+	// Declare every node in package order so the diagram is stable between runs
+	ids := make(map[string]string)
+	var b strings.Builder
+	b.WriteString("graph TD\n")
+	for _, pkg := range packages {
+		if _, ok := importPath(pkg.Dir, modules); !ok {
+			continue
+		}
+		ids[pkg.Dir] = fmt.Sprintf("p%d", len(ids))
+		label := pkg.Dir
+		if label == "." {
+			label = pkg.Name
+		}
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", ids[pkg.Dir], label)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "    %s --> %s\n", ids[e[0]], ids[e[1]])
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// goImporters maps each file of the repository's Go packages to the number
+// of the repository's packages that import its package
+func goImporters(packages []*goPackage, modules map[string]string) map[string]int {
+	imported := make(map[string]int) // directory -> importing packages
+	for _, e := range goImportEdges(packages, modules) {
+		imported[e[1]]++
+	}
+
+	importers := make(map[string]int)
+	for _, pkg := range packages {
+		if imported[pkg.Dir] == 0 {
+			continue
+		}
+		for file := range pkg.Files {
+			importers[file] = imported[pkg.Dir]
+		}
+	}
+	return importers
+}
+
+// goImportEdges lists the imports between the repository's own Go packages as
+// importer and imported directory pairs, sorted
+func goImportEdges(packages []*goPackage, modules map[string]string) [][2]string {
+	dirs := make(map[string]string) // import path -> directory
+	for _, pkg := range packages {
+		if p, ok := importPath(pkg.Dir, modules); ok {
+			dirs[p] = pkg.Dir
+		}
+	}
+
+	var edges [][2]string
+	for _, pkg := range packages {
+		seen := make(map[string]bool)
+		for _, file := range pkg.Files {
+			for _, imp := range file.Imports {
+				target, ok := dirs[strings.Trim(imp.Path.Value, `"`)]
+				if !ok || target == pkg.Dir || seen[target] {
+					continue
+				}
+				seen[target] = true
+				edges = append(edges, [2]string{pkg.Dir, target})
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	return edges
+}
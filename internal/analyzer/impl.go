@@ -3,11 +3,17 @@ package analyzer
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/priyupadhyay/repo-sage/internal/logging"
 	"github.com/priyupadhyay/repo-sage/pkg/git"
 	"github.com/priyupadhyay/repo-sage/pkg/llm"
 )
@@ -19,9 +25,14 @@ type analyzer struct {
 // NewAnalyzer creates a new analyzer instance
 func NewAnalyzer(options AnalyzeOptions) (Analyzer, error) {
 	llmClient, err := llm.NewClient(llm.Config{
-		OpenAIKey: options.OpenAIKey,
-		APIBase:   options.APIBase,
-		Model:     options.Model,
+		OpenAIKey:        options.OpenAIKey,
+		APIBase:          options.APIBase,
+		Model:            options.Model,
+		MaxContinuations: options.MaxContinuations,
+		TokenBudget:      options.TokenBudget,
+		ExtraBody:        options.ExtraBody,
+
+		RequestsPerMinute: options.RequestsPerMinute,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create LLM client: %w", err)
@@ -32,141 +43,310 @@ func NewAnalyzer(options AnalyzeOptions) (Analyzer, error) {
 	}, nil
 }
 
-func (a *analyzer) Analyze(repoPath string, options AnalyzeOptions) (*AnalysisResult, error) {
-	repo, err := git.New(repoPath)
+func (a *analyzer) Analyze(ctx context.Context, repoPath string, options AnalyzeOptions) (*AnalysisResult, error) {
+	repo, err := openRepository(repoPath, options.NoGit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open repository: %w", err)
 	}
+	repo.IgnoreDirs(options.IgnoreDirs...)
+	repo.IncludeSensitive(options.IncludeSensitive...)
+	if err := setFileSource(repo, options.FileSource, options.Untracked); err != nil {
+		return nil, err
+	}
+	if options.Rev != "" {
+		if err := repo.SetRevision(options.Rev); err != nil {
+			return nil, err
+		}
+		slog.Info(fmt.Sprintf("📌 Analyzing %s (%s) instead of the working tree", options.Rev, repo.Revision()[:7]))
+	}
+	if err := repo.SetScope(options.Subdir); err != nil {
+		return nil, err
+	}
+
+	// Look the tag up first so a repository without tags fails before any requests
+	var releaseTag string
+	if options.SinceTag {
+		releaseTag, err = repo.LatestTag()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the latest tag: %w", err)
+		}
+		slog.Info(fmt.Sprintf("🏷  Highlighting changes since %s", releaseTag))
+	}
+
+	if !options.Detailed && (options.KeepChunks != "" || options.ChunkAppendix) {
+		slog.Warn("Chunk analyses are only produced by detailed analysis; add --detailed to keep them")
+	}
+	if !options.Detailed && options.FileSummaries {
+		slog.Warn("File summaries are only produced by detailed analysis; add --detailed to include them")
+	}
 
-	fmt.Println("\n📂 Scanning repository files...")
+	slog.Info("📂 Scanning repository files...")
 	// Get repository files
 	files, err := repo.ListFiles()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list repository files: %w", err)
 	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no analyzable files found in %s", path.Join(repo.Path, repo.Scope()))
+	}
+
+	slog.Info(fmt.Sprintf("Found %d files", len(files)))
+	repoFiles := files
 
-	fmt.Printf("Found %d files\n", len(files))
-	fmt.Println("\n🔍 Analyzing languages...")
+	if options.Since != "" {
+		files, err = filterChangedSince(repo, files, options.Since)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info(fmt.Sprintf("%d files changed since %s", len(files), options.Since))
+	}
+	if options.AffectedSince != "" {
+		files, err = filterAffectedPackages(repo, files, options.AffectedSince)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info(fmt.Sprintf("%d files in affected packages", len(files)))
+	}
+	slog.Info("🔍 Analyzing languages...")
 	// Get language statistics
-	languages, err := repo.GetLanguages()
+	languages, err := repoLanguages(repo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get language statistics: %w", err)
 	}
+	if options.GroupLanguages {
+		languages = git.GroupLanguages(languages)
+	}
 
-	fmt.Printf("Languages detected: %v\n", formatLanguages(languages))
-
-	// Build directory structure
-	dirStructure := buildDirStructure(files)
+	slog.Info(fmt.Sprintf("Languages detected: %v", formatLanguages(languages)))
 
-	// Read important files for quick summary
-	importantFiles := make(map[string]string)
+	license, licenseFiles, err := repo.License()
+	if err != nil {
+		slog.Warn("failed to detect the license", "error", err)
+	} else if len(licenseFiles) > 0 && license == "" {
+		slog.Debug("license not recognized", "files", licenseFiles)
+	}
 
-	// Always include README files
-	for _, file := range files {
-		base := strings.ToLower(filepath.Base(file))
-		if strings.HasPrefix(base, "readme.") {
-			content, err := repo.ReadFile(file)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read file %s: %w", file, err)
-			}
-			importantFiles[file] = string(content)
-			break // Only use the first README found
-		}
+	pipelines := detectCI(files)
+	for _, pipeline := range pipelines {
+		slog.Debug("detected CI/CD configuration", "system", pipeline.System, "files", pipeline.Files)
+	}
+	schemaFiles, migrations := detectDataModel(files)
+	if len(schemaFiles) > 0 || len(migrations) > 0 {
+		slog.Debug("detected data model", "schema_files", schemaFiles, "migrations", migrations)
+	}
+	testFrameworks := detectTestFrameworks(repo, files)
+	for _, framework := range testFrameworks {
+		slog.Debug("detected test framework", "framework", framework.Name, "command", framework.Command)
 	}
 
-	// Add package manifests
-	manifestFiles := []string{
-		"go.mod", "package.json", "requirements.txt", "Cargo.toml",
-		"Gemfile", "composer.json", "pom.xml", "build.gradle",
+	// Build directory structure
+	dirStructure := buildDirStructure(files, options.DirDepth)
+
+	// Ground components and the flow diagram in the code where the language allows it
+	goPackages := parseGoPackages(repo, files)
+	staticComponents := detectComponents(goPackages)
+	modules := goModules(repo, withEnclosingModules(repo.Scope(), repoFiles))
+	importGraph := goImportGraph(goPackages, modules)
+	entryPoints := findEntryPoints(files, append(manifestEntryPoints(repo), goMainFiles(goPackages)...))
+	var primaryEntryPoint string
+	if len(entryPoints) > 0 {
+		primaryEntryPoint = entryPoints[0]
 	}
-	for _, manifest := range manifestFiles {
-		for _, file := range files {
-			if filepath.Base(file) == manifest {
-				content, err := repo.ReadFile(file)
-				if err != nil {
-					return nil, fmt.Errorf("failed to read file %s: %w", file, err)
-				}
-				importantFiles[file] = string(content)
-			}
+
+	// Describe each subproject separately when the repository is a monorepo
+	subprojects := detectSubprojects(repo, repoFiles)
+	if options.GroupLanguages {
+		for i := range subprojects {
+			subprojects[i].Languages = git.GroupLanguages(subprojects[i].Languages)
 		}
 	}
 
-	// Add main/index files if not in detailed mode
-	if !options.Detailed {
-		for _, file := range files {
-			base := filepath.Base(file)
-			if base == "main.go" || base == "index.js" || base == "index.ts" {
-				content, err := repo.ReadFile(file)
-				if err != nil {
-					return nil, fmt.Errorf("failed to read file %s: %w", file, err)
-				}
-				importantFiles[file] = string(content)
-			}
-		}
+	// Read important files for quick summary
+	importantFiles, err := readImportantFiles(repo, files, !options.Detailed, options.DocFiles)
+	if err != nil {
+		return nil, err
 	}
 
 	var fileContents map[string]string
 	if options.Detailed {
-		fmt.Println("\n📖 Reading all files...")
-		// Read all files for detailed analysis
-		fileContents = make(map[string]string)
-		for i, file := range files {
-			fmt.Printf("\r%d/%d files processed", i+1, len(files))
-			content, err := repo.ReadFile(file)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read file %s: %w", file, err)
+		toRead := files
+		if !options.AllFiles {
+			toRead = sourceFiles(files, importantFiles)
+			if skipped := len(files) - len(toRead); skipped > 0 {
+				slog.Info(fmt.Sprintf("Skipping %d non-source files (use --all-files to include them)", skipped))
 			}
-			fileContents[file] = string(content)
 		}
-		fmt.Println()
+
+		slog.Info("📖 Reading all files...")
+		// Read all files for detailed analysis
+		fileContents, err = readFiles(repo, toRead, options.Concurrency)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		fileContents = importantFiles
 	}
 
-	// Prepare analysis input with directory structure
-	analysisInput := fmt.Sprintf("Directory Structure:\n%s\n\nFiles to analyze:\n", dirStructure)
-	for name := range fileContents {
-		analysisInput += fmt.Sprintf("- %s\n", name)
+	if err := readContextFiles(repo, options.ContextFiles, fileContents); err != nil {
+		return nil, err
+	}
+	if !options.NoRedact {
+		redactFiles(fileContents)
+	}
+
+	// Persist chunk results of detailed runs so a failed run can be resumed
+	var checkpoint *fileCheckpoint
+	if options.Detailed {
+		checkpoint, err = prepareCheckpoint(repo.Path, options)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	fmt.Println("\n🤖 Analyzing with AI...")
-	// Analyze with LLM
-	analysis, err := a.llmClient.Analyze(context.Background(), llm.AnalyzeInput{
+	slog.Info("🤖 Analyzing with AI...")
+	// Analyze with LLM, keeping one progress bar per stage. The client serializes
+	// callback invocations, so the bar state below needs no locking.
+	var bar *logging.Progress
+	var barStage string
+	stageProgress := func(stage, label string, current, total int) {
+		if bar == nil || barStage != stage {
+			if bar != nil {
+				bar.Finish()
+			}
+			bar = logging.NewProgress(label, total)
+			barStage = stage
+		}
+		bar.Set(current)
+	}
+	finishProgress := func() {
+		if bar != nil {
+			bar.Finish()
+			bar = nil
+		}
+	}
+
+	input := llm.AnalyzeInput{
 		Files:        fileContents,
 		Languages:    languages,
 		ContextSize:  options.ContextSize,
 		DirStructure: dirStructure,
 		IsDetailed:   options.Detailed,
-	}, func(stage string, current, total int, response string) {
+		SummaryOnly:  options.SummaryOnly,
+		ChunkSize:    options.ChunkSize,
+		ChunkOverlap: options.ChunkOverlap,
+		Concurrency:  options.Concurrency,
+		Language:     options.Language,
+		PromptSuffix: options.PromptSuffix,
+
+		SummarizeFiles:  options.FileSummaries,
+		KnownComponents: toLLMComponents(staticComponents),
+		Subprojects:     subprojectSummaries(subprojects),
+		Importers:       goImporters(goPackages, modules),
+	}
+	if checkpoint != nil {
+		input.Checkpoint = checkpoint
+	}
+
+	analysis, err := a.llmClient.Analyze(ctx, input, func(stage string, current, total int, response string) {
 		switch stage {
 		case "Preparing files":
-			fmt.Printf("\r⚙️  %s... %d/%d", stage, current, total)
+			stageProgress(stage, "⚙️  "+stage, current, total)
 		case "Processing files":
-			fmt.Printf("\r📝 %s... %d/%d", stage, current, total)
+			stageProgress(stage, "📝 "+stage, current, total)
 		case "Analyzing chunks":
-			fmt.Printf("\r🧠 %s... %d/%d", stage, current, total)
+			stageProgress(stage, "🧠 "+stage, current, total)
+		case "Estimated time remaining":
+			if bar != nil && barStage == "Analyzing chunks" {
+				bar.SetDetail("ETA " + response)
+			}
 		case "Analysis response":
-			fmt.Printf("\n\n🔹 Analysis part %d/%d:\n%s\n", current, total, response)
+			slog.Info(fmt.Sprintf("🔹 Analysis part %d/%d:\n%s", current, total, response))
 		case "Generating summary":
-			fmt.Printf("\n\n📊 Generating final summary...\n")
+			finishProgress()
+			slog.Info("📊 Generating final summary...")
 		case "Final summary":
-			fmt.Printf("\n✨ Final Analysis:\n%s\n", response)
+			slog.Info(fmt.Sprintf("✨ Final Analysis:\n%s", response))
+		case "Summarizing files":
+			stageProgress(stage, "📄 "+stage, current, total)
 		}
 	})
+	finishProgress()
 	if err != nil {
+		if checkpoint != nil && checkpoint.Len() > 0 {
+			slog.Warn(fmt.Sprintf("Saved %d completed chunks; rerun with --resume to continue", checkpoint.Len()))
+		}
 		return nil, fmt.Errorf("failed to analyze repository: %w", err)
 	}
 
-	// Convert components
-	components := make([]Component, len(analysis.Components))
-	for i, c := range analysis.Components {
-		components[i] = Component{
-			Name:        c.Name,
-			Type:        c.Type,
-			Path:        c.Path,
-			Description: c.Description,
+	// Keep the chunks of a run stopped by the token budget so it can be resumed
+	if checkpoint != nil && analysis.Incomplete == "" {
+		if err := checkpoint.Remove(); err != nil {
+			slog.Warn(err.Error())
+		}
+	}
+
+	if options.KeepChunks != "" && len(analysis.Chunks) > 0 {
+		if err := writeChunks(options.KeepChunks, analysis.Chunks); err != nil {
+			return nil, err
+		}
+		slog.Info(fmt.Sprintf("🧩 Saved %d chunk analyses to %s", len(analysis.Chunks), options.KeepChunks))
+	}
+	if analysis.Incomplete != "" {
+		slog.Warn(fmt.Sprintf("⚠️  Incomplete analysis: %s", analysis.Incomplete))
+		if checkpoint != nil {
+			slog.Warn("Completed chunks are saved; rerun with --resume and a larger --token-budget to finish")
+		}
+	}
+	var chunkAnalyses []string
+	if options.ChunkAppendix {
+		chunkAnalyses = chunkResponses(analysis.Chunks)
+	}
+
+	var ciSummary string
+	if options.CISummary {
+		switch {
+		case options.SummaryOnly:
+			slog.Warn("Quick mode makes a single request, so the CI/CD configuration is not summarized")
+		case len(pipelines) == 0:
+			slog.Info("No CI/CD configuration found to summarize")
+		default:
+			ciSummary = a.summarizeCI(ctx, repo, pipelines, options)
+		}
+	}
+
+	var dataModel string
+	if options.DataModel {
+		switch {
+		case options.SummaryOnly:
+			slog.Warn("Quick mode makes a single request, so the data model is not summarized")
+		case len(schemaFiles) == 0 && len(migrations) == 0:
+			slog.Info("No schema or migration files found to summarize")
+		default:
+			dataModel = a.summarizeDataModel(ctx, repo, files, schemaFiles, options)
+		}
+	}
+
+	var releaseChanges string
+	if releaseTag != "" {
+		if options.SummaryOnly {
+			slog.Warn("Quick mode makes a single request, so the changes since the latest tag are not summarized")
+		} else {
+			releaseChanges = a.summarizeChangesSinceTag(ctx, repo, releaseTag, options)
 		}
 	}
+	// The optional summaries only log their failures, so report a run stopped
+	// while making them instead of returning a result missing them
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to analyze repository: %w", err)
+	}
+
+	components := mergeComponents(staticComponents, fromLLMComponents(analysis.Components))
+	assignComponents(subprojects, components)
+
+	flowDiagram := analysis.FlowDiagram
+	if importGraph != "" {
+		flowDiagram = importGraph
+	}
 
 	return &AnalysisResult{
 		RepoInfo: RepoInfo{
@@ -174,16 +354,191 @@ func (a *analyzer) Analyze(repoPath string, options AnalyzeOptions) (*AnalysisRe
 			Description:  analysis.Description,
 			Languages:    languages,
 			Components:   components,
-			EntryPoints:  findEntryPoints(files),
+			EntryPoints:  entryPoints,
 			Dependencies: findDependencies(files, fileContents),
+			License:      license,
+			LicenseFiles: licenseFiles,
+			CI:           pipelines,
+			SchemaFiles:  schemaFiles,
+			Migrations:   migrations,
+
+			PrimaryEntryPoint: primaryEntryPoint,
+			TestFrameworks:    testFrameworks,
 		},
 		Architecture:  analysis.Architecture,
 		Setup:         analysis.Setup,
-		FlowDiagram:   analysis.FlowDiagram,
+		FlowDiagram:   flowDiagram,
+		DirStructure:  dirStructure,
+		Subprojects:   subprojects,
+		ChunkAnalyses: chunkAnalyses,
+		FileSummaries: analysis.FileSummaries,
+		CISummary:     ciSummary,
+		DataModel:     dataModel,
+		Incomplete:    analysis.Incomplete,
+		ReleaseTag:    releaseTag,
+		Changes:       releaseChanges,
+		Trimmed:       analysis.Trimmed,
+		Revision:      repo.Revision(),
+		Scope:         repo.Scope(),
+		AnalyzedAt:    time.Now(),
 		GeneratedWith: "repo-sage",
 	}, nil
 }
 
+func (a *analyzer) Components(repoPath string, options AnalyzeOptions) ([]Component, error) {
+	repo, err := openRepository(repoPath, options.NoGit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	repo.IgnoreDirs(options.IgnoreDirs...)
+	repo.IncludeSensitive(options.IncludeSensitive...)
+	if err := setFileSource(repo, options.FileSource, options.Untracked); err != nil {
+		return nil, err
+	}
+
+	slog.Info("📂 Scanning repository files...")
+	files, err := repo.ListFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repository files: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no analyzable files found in %s", repo.Path)
+	}
+
+	languages, err := repoLanguages(repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get language statistics: %w", err)
+	}
+
+	importantFiles, err := readImportantFiles(repo, files, true, options.DocFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := readContextFiles(repo, options.ContextFiles, importantFiles); err != nil {
+		return nil, err
+	}
+	if !options.NoRedact {
+		redactFiles(importantFiles)
+	}
+
+	staticComponents := detectComponents(parseGoPackages(repo, files))
+
+	var found llm.ComponentCallback
+	var flush func()
+	if options.StreamComponents != nil {
+		found, flush = streamMergedComponents(staticComponents, options.StreamComponents)
+	}
+
+	slog.Info("🤖 Identifying components with AI...")
+	identified, err := a.llmClient.IdentifyComponents(context.Background(), llm.AnalyzeInput{
+		Files:        importantFiles,
+		Languages:    languages,
+		ContextSize:  options.ContextSize,
+		DirStructure: buildDirStructure(files, options.DirDepth),
+
+		KnownComponents: toLLMComponents(staticComponents),
+	}, found)
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify components: %w", err)
+	}
+	if flush != nil {
+		flush()
+	}
+
+	return mergeComponents(staticComponents, fromLLMComponents(identified)), nil
+}
+
+// detectComponents lists components found by static analysis, currently for Go only
+func detectComponents(packages []*goPackage) []Component {
+	components := goComponents(packages)
+	if len(components) > 0 {
+		slog.Info(fmt.Sprintf("Found %d Go packages, types and functions", len(components)))
+	}
+	return components
+}
+
+func toLLMComponents(components []Component) []llm.Component {
+	converted := make([]llm.Component, len(components))
+	for i, c := range components {
+		converted[i] = llm.Component{
+			Name:        c.Name,
+			Type:        c.Type,
+			Path:        c.Path,
+			Description: c.Description,
+		}
+	}
+	return converted
+}
+
+func fromLLMComponents(components []llm.Component) []Component {
+	converted := make([]Component, len(components))
+	for i, c := range components {
+		converted[i] = Component{
+			Name:        c.Name,
+			Type:        c.Type,
+			Path:        c.Path,
+			Description: c.Description,
+		}
+	}
+	return converted
+}
+
+// streamMergedComponents returns a callback for the model's components that
+// passes stream the components mergeComponents would return, as early as each
+// is known: without static components the model's own, and otherwise each
+// described static component at once and the rest when the model describes
+// them. flush passes the static components the model never described.
+func streamMergedComponents(static []Component, stream func(Component)) (found llm.ComponentCallback, flush func()) {
+	if len(static) == 0 {
+		return func(c llm.Component) {
+			stream(fromLLMComponents([]llm.Component{c})[0])
+		}, func() {}
+	}
+
+	var pending []Component
+	for _, c := range static {
+		if c.Description != "" {
+			stream(c)
+		} else {
+			pending = append(pending, c)
+		}
+	}
+
+	found = func(described llm.Component) {
+		if described.Description == "" {
+			return
+		}
+		name := strings.ToLower(described.Name)
+		dir := strings.TrimSuffix(filepath.ToSlash(described.Path), "/")
+		kept := pending[:0]
+		for _, c := range pending {
+			if strings.ToLower(c.Name) == name || (c.Path == dir && (c.Type == "package" || c.Type == "command")) {
+				c.Description = described.Description
+				stream(c)
+				continue
+			}
+			kept = append(kept, c)
+		}
+		pending = kept
+	}
+	flush = func() {
+		for _, c := range pending {
+			stream(c)
+		}
+		pending = nil
+	}
+	return found, flush
+}
+
+func (a *analyzer) ListModels() ([]string, error) {
+	models, err := a.llmClient.ListModels(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	return models, nil
+}
+
 func formatLanguages(langs map[string]float64) string {
 	var result string
 	for lang, pct := range langs {
@@ -195,35 +550,45 @@ func formatLanguages(langs map[string]float64) string {
 	return result
 }
 
-func (a *analyzer) ExplainFile(filePath string, options ExplainOptions) (string, error) {
+// findRepository opens the git repository containing a file by walking up
+// from its directory, returning the file's absolute path and its path
+// relative to the repository root
+func findRepository(filePath string) (repo *git.Repository, absPath, relPath string, err error) {
 	// Convert to absolute path if relative
-	absPath, err := filepath.Abs(filePath)
+	absPath, err = filepath.Abs(filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to get absolute path: %w", err)
+		return nil, "", "", fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
 	// Find git repository by walking up the directory tree
 	dir := filepath.Dir(absPath)
-	var repo *git.Repository
 	for {
 		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
 			repo, err = git.New(dir)
 			if err != nil {
-				return "", fmt.Errorf("failed to open repository: %w", err)
+				return nil, "", "", fmt.Errorf("failed to open repository: %w", err)
 			}
 			break
 		}
 		parent := filepath.Dir(dir)
 		if parent == dir {
-			return "", fmt.Errorf("no git repository found in parent directories")
+			return nil, "", "", fmt.Errorf("no git repository found in parent directories")
 		}
 		dir = parent
 	}
 
 	// Get the relative path within the repository
-	relPath, err := filepath.Rel(repo.Path, absPath)
+	relPath, err = filepath.Rel(repo.Path, absPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to get relative path: %w", err)
+		return nil, "", "", fmt.Errorf("failed to get relative path: %w", err)
+	}
+	return repo, absPath, filepath.ToSlash(relPath), nil
+}
+
+func (a *analyzer) ExplainFile(ctx context.Context, filePath string, options ExplainOptions) (string, error) {
+	repo, absPath, relPath, err := findRepository(filePath)
+	if err != nil {
+		return "", err
 	}
 
 	content, err := repo.ReadFile(relPath)
@@ -231,28 +596,456 @@ func (a *analyzer) ExplainFile(filePath string, options ExplainOptions) (string,
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
 
-	explanation, err := a.llmClient.ExplainFile(context.Background(), llm.ExplainInput{
-		Filename:    filepath.Base(absPath),
-		Content:     string(content),
+	filename := filepath.Base(absPath)
+	source, ok := toText(relPath, content)
+	if !ok {
+		return "", fmt.Errorf("%s appears to be a binary file", relPath)
+	}
+	if options.Symbol != "" || options.Line > 0 {
+		s, err := extractSymbol(filename, source, options.Symbol, options.Line)
+		if err != nil {
+			return "", err
+		}
+		label := options.Symbol
+		if label == "" {
+			label = fmt.Sprintf("line %d", options.Line)
+		}
+		slog.Debug("Narrowed file for explanation", "symbol", label, "start", s.StartLine, "end", s.EndLine)
+		filename = fmt.Sprintf("%s (%s, lines %d-%d)", filename, label, s.StartLine, s.EndLine)
+		source = s.Content
+	}
+	if !options.NoRedact {
+		source, _ = redactSecrets(absPath, source)
+	}
+
+	input := llm.ExplainInput{
+		Filename:    filename,
+		Content:     source,
 		ContextSize: options.ContextSize,
-	})
+		Language:    options.Language,
+
+		PromptSuffix: options.PromptSuffix,
+	}
+
+	// Reuse the explanation of unchanged content unless caching is disabled
+	cachePath := ""
+	if !options.NoCache {
+		cachePath, err = explanationCachePath(options.Model, input)
+		if err != nil {
+			slog.Debug("explanation cache disabled", "error", err)
+		} else if cached, ok := loadExplanation(cachePath); ok {
+			slog.Debug("using cached explanation", "path", cachePath)
+			if options.Stream != nil {
+				options.Stream(cached)
+			}
+			return cached, nil
+		}
+	}
+
+	explanation, err := a.llmClient.ExplainFile(ctx, input, options.Stream)
 	if err != nil {
 		return "", fmt.Errorf("failed to explain file: %w", err)
 	}
 
+	if cachePath != "" {
+		saveExplanation(cachePath, explanation.Explanation)
+	}
 	return explanation.Explanation, nil
 }
 
-// findEntryPoints identifies potential entry points in the repository
-func findEntryPoints(files []string) []string {
-	var entryPoints []string
+// maxChangelogDiffSize bounds the diff included per commit so long histories fit in the prompt
+const maxChangelogDiffSize = 2000
+
+func (a *analyzer) Changelog(repoPath string, options ChangelogOptions) (string, error) {
+	repo, err := git.New(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	slog.Info("📜 Reading commit history...")
+	commits, err := repo.Log(git.LogOptions{
+		Range:       options.Range,
+		Limit:       options.Count,
+		WithDiff:    options.WithDiffs,
+		MaxDiffSize: maxChangelogDiffSize,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read commits: %w", err)
+	}
+	if len(commits) == 0 {
+		return "", fmt.Errorf("no commits found")
+	}
+
+	slog.Info(fmt.Sprintf("Found %d commits", len(commits)))
+
+	input := llm.ChangelogInput{
+		ContextSize: options.ContextSize,
+	}
+	for _, c := range commits {
+		input.Commits = append(input.Commits, llm.ChangelogCommit{
+			Hash:    c.Hash,
+			Subject: c.Subject,
+			Body:    c.Body,
+			Diff:    c.Diff,
+		})
+	}
+
+	slog.Info("🤖 Summarizing changes with AI...")
+	output, err := a.llmClient.Changelog(context.Background(), input)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate changelog: %w", err)
+	}
+
+	return output.Changelog, nil
+}
+
+// readImportantFiles reads the README, documentation matching docPatterns,
+// package manifests and, optionally, the main/index entry files that seed a
+// quick summary. A nil docPatterns uses DefaultDocFiles.
+func readImportantFiles(repo *git.Repository, files []string, includeEntryFiles bool, docPatterns []string) (map[string]string, error) {
+	importantFiles := make(map[string]string)
+
+	// Always include the most relevant README, without badges and other noise
+	readme := selectReadme(files)
+	if readme != "" {
+		content, err := repo.ReadFile(readme)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", readme, err)
+		}
+		if text, ok := toText(readme, content); ok {
+			importantFiles[readme] = cleanReadme(readme, text)
+		}
+	}
+
+	// Add high-level documentation, which often describes the project best
+	if docPatterns == nil {
+		docPatterns = DefaultDocFiles
+	}
+	docs := selectDocFiles(files, docPatterns, readme)
+	if len(docs) > 0 {
+		slog.Debug("including documentation files", "files", docs)
+	}
+
+	// Add package manifests
+	for _, file := range append(docs, selectManifests(files)...) {
+		content, err := repo.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", file, err)
+		}
+		if text, ok := toText(file, content); ok {
+			importantFiles[file] = text
+		}
+	}
+
+	// Add main/index files
+	if includeEntryFiles {
+		for _, file := range files {
+			base := filepath.Base(file)
+			if base == "main.go" || base == "index.js" || base == "index.ts" {
+				content, err := repo.ReadFile(file)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read file %s: %w", file, err)
+				}
+				if text, ok := toText(file, content); ok {
+					importantFiles[file] = text
+				}
+			}
+		}
+	}
+
+	return importantFiles, nil
+}
+
+// readFiles reads files in parallel, using one worker per CPU unless concurrency is set
+func readFiles(repo *git.Repository, files []string, concurrency int) (map[string]string, error) {
+	workers := concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var (
+		mu       sync.Mutex
+		contents = make(map[string]string, len(files))
+		binaries int
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	bar := logging.NewProgress("Files processed", len(files))
+	defer bar.Finish()
+
+	jobs := make(chan string)
+	for w := 0; w < workers && w < len(files); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				content, err := repo.ReadFile(file)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("failed to read file %s: %w", file, err)
+					}
+				} else if text, ok := toText(file, content); ok {
+					contents[file] = text
+				} else {
+					binaries++
+					slog.Debug("skipping binary file", "path", file)
+				}
+				mu.Unlock()
+				slog.Debug("read file", "path", file, "bytes", len(content))
+				bar.Increment()
+			}
+		}()
+	}
+	for _, file := range files {
+		jobs <- file
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if binaries > 0 {
+		slog.Warn(fmt.Sprintf("Skipped %d binary files", binaries))
+	}
+	return contents, nil
+}
+
+// openRepository opens a git repository, or with noGit any directory
+func openRepository(path string, noGit bool) (*git.Repository, error) {
+	if noGit {
+		return git.NewDirectory(path)
+	}
+	return git.New(path)
+}
+
+// setFileSource selects how the repository lists files; an empty name keeps
+// the default. untracked adds files git does not track to the "git" source.
+func setFileSource(repo *git.Repository, name string, untracked bool) error {
+	repo.SetIncludeUntracked(untracked)
+	if name == "" {
+		return nil
+	}
+	source, err := git.ParseFileSource(name)
+	if err != nil {
+		return err
+	}
+	repo.SetFileSource(source)
+	return nil
+}
+
+// sourceFiles keeps the files in a recognized language, plus the README and
+// manifests already chosen as important, dropping lock files and other data
+func sourceFiles(files []string, important map[string]string) []string {
+	var kept []string
+	for _, file := range files {
+		if _, ok := important[file]; ok || git.IsSourceFile(file) {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// prepareCheckpoint opens the checkpoint for a detailed run, reusing stored chunk
+// results when resuming and discarding those of an earlier interrupted run otherwise
+func prepareCheckpoint(repoPath string, options AnalyzeOptions) (*fileCheckpoint, error) {
+	path, err := checkpointPath(repoPath, options.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint, err := openCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkpoint.Len() == 0 {
+		return checkpoint, nil
+	}
+
+	if options.Resume {
+		slog.Info(fmt.Sprintf("♻️  Resuming interrupted run with %d completed chunks", checkpoint.Len()))
+		return checkpoint, nil
+	}
+
+	slog.Warn(fmt.Sprintf("Found an interrupted run with %d completed chunks; starting over (use --resume to continue it)", checkpoint.Len()))
+	if err := checkpoint.Remove(); err != nil {
+		return nil, err
+	}
+	return openCheckpoint(path)
+}
+
+// filterChangedSince keeps only the files that changed since the given commit or date
+func filterChangedSince(repo *git.Repository, files []string, since string) ([]string, error) {
+	changed, err := repo.ChangedSince(since)
+	if err != nil {
+		return nil, err
+	}
+
+	changedSet := make(map[string]bool, len(changed))
+	for _, file := range changed {
+		changedSet[file] = true
+	}
+
+	var filtered []string
+	for _, file := range files {
+		if changedSet[file] {
+			filtered = append(filtered, file)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil, fmt.Errorf("no analyzable files changed since %s", since)
+	}
+
+	return filtered, nil
+}
+
+// readContextFiles adds user-selected files to contents regardless of the
+// importance heuristics; paths may be absolute or relative to the repository
+func readContextFiles(repo *git.Repository, paths []string, contents map[string]string) error {
+	for _, path := range paths {
+		relPath := path
+		if filepath.IsAbs(path) {
+			rel, err := filepath.Rel(repo.Path, path)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				return fmt.Errorf("context file %s is outside the repository", path)
+			}
+			relPath = rel
+		}
+		relPath = filepath.ToSlash(filepath.Clean(relPath))
+
+		content, err := repo.ReadFile(relPath)
+		if err != nil {
+			return fmt.Errorf("failed to read context file %s: %w", path, err)
+		}
+		text, ok := toText(relPath, content)
+		if !ok {
+			return fmt.Errorf("context file %s is binary", path)
+		}
+		contents[relPath] = text
+		slog.Debug("included context file", "path", relPath)
+	}
+	return nil
+}
+
+// readmePriority ranks README variants; lower is preferred
+var readmePriority = map[string]int{
+	"readme.md":  0,
+	"readme.rst": 1,
+	"readme.txt": 2,
+}
+
+// selectReadme picks a single README deterministically, preferring files closer
+// to the repository root and then Markdown over reStructuredText over plain text
+func selectReadme(files []string) string {
+	best := ""
+	bestDepth, bestRank := 0, 0
+	for _, file := range files {
+		base := strings.ToLower(filepath.Base(file))
+		if base != "readme" && !strings.HasPrefix(base, "readme.") {
+			continue
+		}
+
+		depth := strings.Count(file, "/")
+		rank, ok := readmePriority[base]
+		if !ok {
+			rank = len(readmePriority)
+		}
+
+		if best == "" || depth < bestDepth ||
+			(depth == bestDepth && rank < bestRank) ||
+			(depth == bestDepth && rank == bestRank && file < best) {
+			best, bestDepth, bestRank = file, depth, rank
+		}
+	}
+	return best
+}
+
+// DefaultDocFiles are the patterns of documentation files added to the quick
+// summary when present, in order of preference. Patterns are matched, ignoring
+// case, against paths relative to the repository root.
+var DefaultDocFiles = []string{
+	"ARCHITECTURE.md", "docs/ARCHITECTURE.md", "DESIGN.md", "docs/DESIGN.md",
+	"OVERVIEW.md", "docs/overview.md", "docs/index.md", "docs/README.md",
+	"CONTRIBUTING.md", "docs/*.md",
+}
+
+// maxDocFiles caps how many documentation files are sent, keeping the quick
+// summary within small contexts
+const maxDocFiles = 3
+
+// selectDocFiles returns up to maxDocFiles files matching the patterns, taking
+// the patterns in order and skipping the README already included
+func selectDocFiles(files, patterns []string, readme string) []string {
+	sorted := append([]string(nil), files...)
+	sort.Strings(sorted)
+
+	var selected []string
+	seen := map[string]bool{readme: true}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		for _, file := range sorted {
+			if len(selected) == maxDocFiles {
+				return selected
+			}
+			if seen[file] {
+				continue
+			}
+			if matched, _ := path.Match(pattern, strings.ToLower(file)); matched {
+				selected = append(selected, file)
+				seen[file] = true
+			}
+		}
+	}
+	return selected
+}
+
+// manifestFiles lists the package manifests recognised across ecosystems
+var manifestFiles = map[string]bool{
+	"go.mod": true, "package.json": true, "requirements.txt": true, "Cargo.toml": true,
+	"Gemfile": true, "composer.json": true, "pom.xml": true, "build.gradle": true,
+}
+
+// workspaceFiles describe a monorepo layout and are only meaningful at the root
+var workspaceFiles = map[string]bool{
+	"go.work": true, "pnpm-workspace.yaml": true, "lerna.json": true, "settings.gradle": true,
+}
+
+// maxNestedManifests caps how many per-package manifests are sent to the LLM
+const maxNestedManifests = 8
+
+// selectManifests returns the root manifests and workspace files, followed by
+// the shallowest per-package manifests up to maxNestedManifests
+func selectManifests(files []string) []string {
+	var root, nested []string
 	for _, file := range files {
 		base := filepath.Base(file)
-		if base == "main.go" || base == "index.js" || base == "app.py" {
-			entryPoints = append(entryPoints, file)
+		isRoot := filepath.Dir(file) == "."
+		switch {
+		case isRoot && (manifestFiles[base] || workspaceFiles[base]):
+			root = append(root, file)
+		case !isRoot && manifestFiles[base]:
+			nested = append(nested, file)
 		}
 	}
-	return entryPoints
+
+	sort.Strings(root)
+	sort.Slice(nested, func(i, j int) bool {
+		di := strings.Count(nested[i], "/")
+		dj := strings.Count(nested[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return nested[i] < nested[j]
+	})
+
+	if len(nested) > maxNestedManifests {
+		slog.Debug("skipping nested manifests", "found", len(nested), "included", maxNestedManifests)
+		nested = nested[:maxNestedManifests]
+	}
+
+	return append(root, nested...)
 }
 
 // findDependencies extracts dependencies from common dependency files
@@ -261,35 +1054,132 @@ func findDependencies(files []string, contents map[string]string) map[string]str
 	return map[string]string{}
 }
 
-func buildDirStructure(files []string) string {
-	// Create a map to store directory structure
-	dirs := make(map[string]bool)
+// maxDirStructureLines and maxDirStructureBytes bound the rendered directory
+// tree, which would otherwise fill the context window on very large repositories
+const (
+	maxDirStructureLines = 400
+	maxDirStructureBytes = 16 * 1024
+)
+
+// buildDirStructure renders the directories containing files as a tree. Directories
+// nested deeper than maxDepth levels are collapsed into a single "…" entry under
+// their deepest shown ancestor; a maxDepth of 0 or less renders the full tree.
+// Trees larger than maxDirStructureLines or maxDirStructureBytes keep the
+// shallowest and most populated directories, noting how many were left out.
+func buildDirStructure(files []string, maxDepth int) string {
+	// Count the files under each directory, including its subdirectories
+	counts := make(map[string]int)
 	for _, file := range files {
-		dir := filepath.Dir(file)
+		dir := path.Dir(file)
 		for dir != "." && dir != "/" {
-			dirs[dir] = true
-			dir = filepath.Dir(dir)
+			counts[dir]++
+			dir = path.Dir(dir)
 		}
 	}
 
-	// Convert to tree structure
-	var result strings.Builder
-	result.WriteString(".\n")
+	// Directories past maxDepth are hidden behind a marker on their shown ancestor
+	collapsed := make(map[string]bool)
+	var dirs []string
+	for dir := range counts {
+		if maxDepth > 0 && strings.Count(dir, "/") >= maxDepth {
+			parent := dir
+			for strings.Count(parent, "/") >= maxDepth {
+				parent = path.Dir(parent)
+			}
+			collapsed[parent] = true
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+
+	// Shallow directories come first, then the ones holding the most files
+	sort.Slice(dirs, func(i, j int) bool {
+		di := strings.Count(dirs[i], "/")
+		dj := strings.Count(dirs[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		if counts[dirs[i]] != counts[dirs[j]] {
+			return counts[dirs[i]] > counts[dirs[j]]
+		}
+		return dirs[i] < dirs[j]
+	})
 
-	// Convert map to sorted slice for consistent output
-	var paths []string
-	for path := range dirs {
-		paths = append(paths, path)
+	// Take directories in priority order while the tree stays within the limits.
+	// A parent always precedes its children, so the last one taken is a leaf and
+	// can be dropped again if the omission markers push the tree over.
+	var shown []string
+	lines, size := 1, len(".\n")
+	included := make(map[string]bool)
+	for _, dir := range dirs {
+		parent := path.Dir(dir)
+		if parent != "." && !included[parent] {
+			continue
+		}
+		entry := strings.Count(dir, "/")*2 + len("└── \n") + len(path.Base(dir))
+		if lines == maxDirStructureLines {
+			break
+		}
+		if size+entry > maxDirStructureBytes {
+			continue
+		}
+		lines++
+		size += entry
+		included[dir] = true
+		shown = append(shown, dir)
 	}
-	sort.Strings(paths)
 
-	for _, path := range paths {
-		depth := strings.Count(path, string(os.PathSeparator))
-		result.WriteString(strings.Repeat("  ", depth))
-		result.WriteString("└── ")
-		result.WriteString(filepath.Base(path))
-		result.WriteString("\n")
+	subdirs := make(map[string]int)
+	for _, dir := range dirs {
+		subdirs[path.Dir(dir)]++
+	}
+
+	tree := renderDirTree(shown, subdirs, collapsed)
+	for len(shown) > 0 && (strings.Count(tree, "\n") > maxDirStructureLines || len(tree) > maxDirStructureBytes) {
+		shown = shown[:len(shown)-1]
+		tree = renderDirTree(shown, subdirs, collapsed)
+	}
+	if len(shown) < len(dirs) {
+		slog.Debug("truncating directory structure", "directories", len(dirs), "included", len(shown))
+	}
+	return tree
+}
+
+// renderDirTree writes the shown directories as a tree. Directories whose
+// subdirectories were collapsed get a "…" entry, and those with subdirectories
+// left out to fit the limits get one saying how many are missing.
+func renderDirTree(shown []string, subdirs map[string]int, collapsed map[string]bool) string {
+	children := make(map[string][]string)
+	for _, dir := range shown {
+		parent := path.Dir(dir)
+		children[parent] = append(children[parent], dir)
 	}
 
+	var result strings.Builder
+	result.WriteString(".\n")
+	var walk func(dir string, depth int)
+	walk = func(dir string, depth int) {
+		sub := children[dir]
+		sort.Strings(sub)
+		for _, child := range sub {
+			result.WriteString(strings.Repeat("  ", depth))
+			result.WriteString("└── ")
+			result.WriteString(path.Base(child))
+			result.WriteString("\n")
+			walk(child, depth+1)
+		}
+		if missing := subdirs[dir] - len(sub); missing > 0 {
+			result.WriteString(strings.Repeat("  ", depth))
+			if missing == 1 {
+				result.WriteString("└── … (1 more directory)\n")
+			} else {
+				fmt.Fprintf(&result, "└── … (%d more directories)\n", missing)
+			}
+		} else if collapsed[dir] {
+			result.WriteString(strings.Repeat("  ", depth))
+			result.WriteString("└── …\n")
+		}
+	}
+	walk(".", 0)
 	return result.String()
 }
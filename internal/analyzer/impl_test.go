@@ -0,0 +1,63 @@
+package analyzer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSelectReadme(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{
+			name:  "Markdown over reStructuredText and text",
+			files: []string{"README.txt", "readme.rst", "README.md", "main.go"},
+			want:  "README.md",
+		},
+		{
+			name:  "reStructuredText over text",
+			files: []string{"README.txt", "readme.rst"},
+			want:  "readme.rst",
+		},
+		{
+			name:  "root README over a nested Markdown one",
+			files: []string{"docs/README.md", "README.txt"},
+			want:  "README.txt",
+		},
+		{
+			name:  "shallowest nested README",
+			files: []string{"services/api/docs/README.md", "docs/README.md", "main.go"},
+			want:  "docs/README.md",
+		},
+		{
+			name:  "known variants over other extensions",
+			files: []string{"README.adoc", "README.txt", "README"},
+			want:  "README.txt",
+		},
+		{
+			name:  "other extensions by name",
+			files: []string{"README.org", "README.adoc"},
+			want:  "README.adoc",
+		},
+		{
+			name:  "no README",
+			files: []string{"main.go", "docs/guide.md", "readme-images/logo.png"},
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectReadme(tt.files); got != tt.want {
+				t.Errorf("selectReadme(%q) = %q, want %q", tt.files, got, tt.want)
+			}
+			// The listing order must not change the choice
+			reversed := slices.Clone(tt.files)
+			slices.Reverse(reversed)
+			if got := selectReadme(reversed); got != tt.want {
+				t.Errorf("selectReadme(%q) = %q, want %q", reversed, got, tt.want)
+			}
+		})
+	}
+}
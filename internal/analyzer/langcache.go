@@ -0,0 +1,71 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/priyupadhyay/repo-sage/pkg/git"
+)
+
+// languageCache is the on-disk record of a repository's language statistics
+type languageCache struct {
+	Key       string             `json:"key"`
+	Languages map[string]float64 `json:"languages"`
+}
+
+// languageCachePath returns the language statistics cache file for a repository
+func languageCachePath(repoPath string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get cache directory: %w", err)
+	}
+	sum := sha256.Sum256([]byte(repoPath))
+	return filepath.Join(cacheDir, "repo-sage", "languages", hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// repoLanguages returns the repository's language statistics, reusing the
+// cached result while HEAD and the working tree are unchanged. Cache failures
+// only cost the speed-up, so they are logged rather than returned.
+func repoLanguages(repo *git.Repository) (map[string]float64, error) {
+	key, err := repo.StateKey()
+	if err != nil {
+		slog.Debug("language cache disabled", "error", err)
+		return repo.GetLanguages()
+	}
+	path, err := languageCachePath(repo.Path)
+	if err != nil {
+		slog.Debug("language cache disabled", "error", err)
+		return repo.GetLanguages()
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		var cached languageCache
+		if err := json.Unmarshal(data, &cached); err == nil && cached.Key == key && cached.Languages != nil {
+			slog.Debug("using cached language statistics", "path", path)
+			return cached.Languages, nil
+		}
+	}
+
+	languages, err := repo.GetLanguages()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(languageCache{Key: key, Languages: languages})
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		slog.Debug("failed to cache language statistics", "error", err)
+	}
+
+	return languages, nil
+}
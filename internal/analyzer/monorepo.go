@@ -0,0 +1,186 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/priyupadhyay/repo-sage/pkg/git"
+)
+
+// detectSubprojects finds the subprojects of a monorepo: the shallowest
+// directories with their own manifest. A repository counts as a monorepo when it
+// has a workspace configuration, or manifests under at least two top-level
+// directories. It returns nil for single-project repositories.
+func detectSubprojects(repo *git.Repository, files []string) []Subproject {
+	workspace := ""
+	var manifests []string
+	for _, file := range files {
+		base := path.Base(file)
+		if path.Dir(file) == "." {
+			if workspaceFiles[base] || isWorkspaceManifest(repo, file) {
+				workspace = base
+			}
+			continue
+		}
+		if manifestFiles[base] {
+			manifests = append(manifests, file)
+		}
+	}
+
+	// Keep the shallowest manifest of each subtree
+	sort.Slice(manifests, func(i, j int) bool {
+		di, dj := strings.Count(manifests[i], "/"), strings.Count(manifests[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return manifests[i] < manifests[j]
+	})
+	var subprojects []Subproject
+	topLevel := make(map[string]bool)
+	for _, manifest := range manifests {
+		dir := path.Dir(manifest)
+		if containedIn(dir, subprojects) {
+			continue
+		}
+		subprojects = append(subprojects, Subproject{
+			Name:     path.Base(dir),
+			Path:     dir,
+			Manifest: path.Base(manifest),
+		})
+		topLevel[strings.SplitN(dir, "/", 2)[0]] = true
+	}
+
+	if workspace == "" && len(topLevel) < 2 {
+		return nil
+	}
+	if len(subprojects) == 0 {
+		return nil
+	}
+
+	sort.Slice(subprojects, func(i, j int) bool {
+		return subprojects[i].Path < subprojects[j].Path
+	})
+	for i := range subprojects {
+		describeSubproject(repo, files, &subprojects[i])
+	}
+
+	slog.Info(fmt.Sprintf("Detected a monorepo with %d subprojects", len(subprojects)))
+	return subprojects
+}
+
+// isWorkspaceManifest reports whether a root manifest declares workspaces, as
+// Cargo workspaces and npm or Yarn workspaces do
+func isWorkspaceManifest(repo *git.Repository, file string) bool {
+	var marker string
+	switch filepath.Base(file) {
+	case "Cargo.toml":
+		marker = "[workspace]"
+	case "package.json":
+		marker = `"workspaces"`
+	default:
+		return false
+	}
+	content, err := repo.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(content), marker)
+}
+
+// containedIn reports whether dir is inside one of the subprojects
+func containedIn(dir string, subprojects []Subproject) bool {
+	for _, s := range subprojects {
+		if dir == s.Path || strings.HasPrefix(dir, s.Path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// describeSubproject fills in the name, description, languages and entry points
+// of a subproject from its files and manifest
+func describeSubproject(repo *git.Repository, files []string, s *Subproject) {
+	var own []string
+	for _, file := range files {
+		if strings.HasPrefix(file, s.Path+"/") {
+			own = append(own, file)
+		}
+	}
+
+	if languages, err := repo.LanguagesOf(own); err == nil {
+		s.Languages = languages
+	} else {
+		slog.Debug("failed to get subproject languages", "path", s.Path, "error", err)
+	}
+	s.EntryPoints = findEntryPoints(own, nil)
+
+	content, err := repo.ReadFile(s.Path + "/" + s.Manifest)
+	if err != nil {
+		return
+	}
+	name, description := manifestInfo(s.Manifest, string(content))
+	if name != "" {
+		s.Name = name
+	}
+	s.Description = description
+}
+
+var (
+	tomlNamePattern        = regexp.MustCompile(`(?m)^name\s*=\s*"([^"]+)"`)
+	tomlDescriptionPattern = regexp.MustCompile(`(?m)^description\s*=\s*"([^"]+)"`)
+	goModulePattern        = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+)
+
+// manifestInfo extracts the declared name and description from a manifest, where the format has them
+func manifestInfo(manifest, content string) (name, description string) {
+	switch manifest {
+	case "package.json", "composer.json":
+		var parsed struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		}
+		if json.Unmarshal([]byte(content), &parsed) == nil {
+			return parsed.Name, parsed.Description
+		}
+	case "Cargo.toml":
+		if m := tomlNamePattern.FindStringSubmatch(content); m != nil {
+			name = m[1]
+		}
+		if m := tomlDescriptionPattern.FindStringSubmatch(content); m != nil {
+			description = m[1]
+		}
+	case "go.mod":
+		if m := goModulePattern.FindStringSubmatch(content); m != nil {
+			name = m[1]
+		}
+	}
+	return name, description
+}
+
+// assignComponents gives each subproject the components located inside it
+func assignComponents(subprojects []Subproject, components []Component) {
+	for i := range subprojects {
+		prefix := subprojects[i].Path
+		for _, c := range components {
+			p := strings.TrimSuffix(filepath.ToSlash(c.Path), "/")
+			if p == prefix || strings.HasPrefix(p, prefix+"/") {
+				subprojects[i].Components = append(subprojects[i].Components, c)
+			}
+		}
+	}
+}
+
+// subprojectSummaries describes subprojects for the prompt, e.g. "web (package.json)"
+func subprojectSummaries(subprojects []Subproject) []string {
+	summaries := make([]string, len(subprojects))
+	for i, s := range subprojects {
+		summaries[i] = fmt.Sprintf("%s (%s)", s.Path, s.Manifest)
+	}
+	return summaries
+}
@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// htmlCommentRe matches HTML comments that open and close on one line
+	htmlCommentRe = regexp.MustCompile(`<!--.*?-->`)
+
+	// badgeLineRe matches lines made only of images, linked images such as
+	// CI and version badges, and HTML tags without text between them
+	badgeLineRe = regexp.MustCompile(`^(\s*(\[!\[[^\]]*\]\([^)]*\)\]\([^)]*\)|!\[[^\]]*\]\([^)]*\)|</?[a-zA-Z][a-zA-Z0-9-]*(\s[^>]*)?/?>))+\s*$`)
+
+	// tocItemRe matches a list item that only links to a heading of the same page
+	tocItemRe = regexp.MustCompile(`^\s*([-*+]|\d+\.)\s+\[[^\]]+\]\(#[^)]*\)\s*$`)
+
+	// tocHeadingRe matches the heading of a table of contents
+	tocHeadingRe = regexp.MustCompile(`(?i)^#{1,6}\s*(table of contents|contents|toc)\s*$`)
+)
+
+// cleanReadme removes what a model reading a Markdown README for the project's
+// description does not need: HTML comments, lines of badges, images and bare
+// HTML tags, and tables of contents of two or more links to headings. Code
+// blocks are left alone, and other lines are kept even if they contain images
+// or links, so that no prose is lost.
+func cleanReadme(name, content string) string {
+	if ext := strings.ToLower(path.Ext(name)); ext != ".md" && ext != ".markdown" {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	kept := make([]string, 0, len(lines))
+	inCode, inComment := false, false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if !inComment && strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+		}
+		if inCode {
+			kept = append(kept, line)
+			continue
+		}
+
+		if inComment {
+			end := strings.Index(line, "-->")
+			if end == -1 {
+				continue
+			}
+			line, inComment = line[end+len("-->"):], false
+		}
+		line = htmlCommentRe.ReplaceAllString(line, "")
+		if start := strings.Index(line, "<!--"); start != -1 {
+			line, inComment = line[:start], true
+		}
+
+		if badgeLineRe.MatchString(line) {
+			continue
+		}
+
+		// A table of contents is a run of heading links, possibly under its own heading
+		end := i
+		for end < len(lines) && tocItemRe.MatchString(lines[end]) {
+			end++
+		}
+		if end-i >= 2 {
+			if tocHeadingRe.MatchString(strings.TrimSpace(lastNonBlank(kept))) {
+				kept = trimToHeading(kept)
+			}
+			i = end - 1
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	// Collapse the blank lines left behind
+	var b strings.Builder
+	blank := 0
+	for _, line := range kept {
+		if strings.TrimSpace(line) == "" {
+			blank++
+			if blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return strings.TrimSpace(b.String()) + "\n"
+}
+
+// lastNonBlank returns the last line that is not blank
+func lastNonBlank(lines []string) string {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.TrimSpace(lines[i]) != "" {
+			return lines[i]
+		}
+	}
+	return ""
+}
+
+// trimToHeading drops the last non-blank line, the table of contents heading,
+// and the blank lines after it
+func trimToHeading(lines []string) []string {
+	i := len(lines) - 1
+	for i >= 0 && strings.TrimSpace(lines[i]) == "" {
+		i--
+	}
+	return lines[:max(i, 0)]
+}
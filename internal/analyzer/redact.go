@@ -0,0 +1,142 @@
+package analyzer
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redacted is the placeholder for secrets removed from file contents before they are sent to the model
+const redacted = "<REDACTED>"
+
+var (
+	// PEM-encoded private keys
+	pemBlockPattern = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`)
+
+	// Well-known token formats: AWS access key IDs, GitHub, Slack and OpenAI-style keys
+	tokenPattern = regexp.MustCompile(`\b(?:(?:AKIA|ASIA)[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{36,}|xox[abposr]-[A-Za-z0-9-]{10,}|sk-[A-Za-z0-9_-]{20,})\b`)
+
+	// Quoted values assigned to secret-looking names in any language, e.g. password = "hunter22"
+	quotedSecretPattern = regexp.MustCompile(`(?i)([\w.-]*(?:password|passwd|secret|api[_-]?key|access[_-]?key|auth[_-]?token|token)[\w.-]*["']?\s*(?::=|=>|[:=])\s*)(["'])([^"'\s]{6,})(["'])`)
+
+	// Unquoted values in configuration files, e.g. DB_PASSWORD=hunter22
+	configSecretPattern = regexp.MustCompile(`(?im)^(\s*(?:export\s+)?[\w.-]*(?:password|passwd|secret|api[_-]?key|access[_-]?key|auth[_-]?token|token)[\w.-]*\s*[:=]\s*)([^\s"'#]{6,})\s*$`)
+
+	// Candidates for the entropy check: long runs of base64 or URL-safe characters
+	entropyCandidatePattern = regexp.MustCompile(`[A-Za-z0-9+/_=-]{32,}`)
+)
+
+// minSecretEntropy is the Shannon entropy in bits per character above which a
+// long token is treated as a secret. Hex digests top out at 4, so commit hashes
+// and checksums are left alone.
+const minSecretEntropy = 4.5
+
+// configExtensions are file types whose unquoted key=value pairs are checked for secrets
+var configExtensions = map[string]bool{
+	".env":        true,
+	".ini":        true,
+	".cfg":        true,
+	".conf":       true,
+	".properties": true,
+	".toml":       true,
+	".yaml":       true,
+	".yml":        true,
+}
+
+// redactSecrets replaces likely secrets in content with a placeholder and
+// returns the redacted content with the number of replacements
+func redactSecrets(filename, content string) (string, int) {
+	count := 0
+	replace := func(pattern *regexp.Regexp, s string, repl func([]string) string) string {
+		return pattern.ReplaceAllStringFunc(s, func(match string) string {
+			count++
+			return repl(pattern.FindStringSubmatch(match))
+		})
+	}
+
+	content = replace(pemBlockPattern, content, func([]string) string { return redacted })
+	content = replace(tokenPattern, content, func([]string) string { return redacted })
+	content = replace(quotedSecretPattern, content, func(m []string) string {
+		return m[1] + m[2] + redacted + m[4]
+	})
+	if isConfigFile(filename) {
+		content = replace(configSecretPattern, content, func(m []string) string {
+			return m[1] + redacted
+		})
+	}
+	if !isChecksumFile(filename) {
+		content = entropyCandidatePattern.ReplaceAllStringFunc(content, func(match string) string {
+			if !isHighEntropy(match) {
+				return match
+			}
+			count++
+			return redacted
+		})
+	}
+
+	return content, count
+}
+
+// redactFiles redacts secrets in place across all file contents
+func redactFiles(contents map[string]string) {
+	names := make([]string, 0, len(contents))
+	for name := range contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	total, files := 0, 0
+	for _, name := range names {
+		content, n := redactSecrets(name, contents[name])
+		if n == 0 {
+			continue
+		}
+		contents[name] = content
+		total += n
+		files++
+		slog.Debug("redacted possible secrets", "path", name, "count", n)
+	}
+
+	if total > 0 {
+		slog.Info(fmt.Sprintf("🔒 Redacted %d possible secrets in %d files (disable with --no-redact)", total, files))
+	}
+}
+
+// isConfigFile reports whether a file holds configuration such as .env or YAML
+func isConfigFile(filename string) bool {
+	base := strings.ToLower(filepath.Base(filename))
+	if base == ".env" || strings.HasPrefix(base, ".env.") {
+		return true
+	}
+	return configExtensions[filepath.Ext(base)]
+}
+
+// isChecksumFile reports whether a file is a lock or checksum file, whose
+// integrity hashes are random-looking but not secret
+func isChecksumFile(filename string) bool {
+	base := strings.ToLower(filepath.Base(filename))
+	return strings.HasSuffix(base, ".sum") || strings.HasSuffix(base, ".lock") ||
+		strings.HasSuffix(base, "-lock.json") || strings.HasSuffix(base, "-lock.yaml")
+}
+
+// isHighEntropy reports whether s mixes letters and digits with enough entropy to look random
+func isHighEntropy(s string) bool {
+	if !strings.ContainsAny(s, "0123456789") || strings.ToLower(s) == s || strings.ToUpper(s) == s {
+		return false
+	}
+
+	counts := make(map[rune]int)
+	for _, ch := range s {
+		counts[ch]++
+	}
+	entropy := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= minSecretEntropy
+}
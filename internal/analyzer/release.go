@@ -0,0 +1,68 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/priyupadhyay/repo-sage/pkg/git"
+	"github.com/priyupadhyay/repo-sage/pkg/llm"
+)
+
+// maxReleaseCommits bounds the commits summarized since the latest tag; the
+// newest are kept, each with a diff truncated to maxChangelogDiffSize
+const maxReleaseCommits = 30
+
+// summarizeChangesSinceTag summarizes the commits made after tag, for a
+// "Changes since <tag>" section. The summary is optional, so failures are
+// logged and leave it empty.
+func (a *analyzer) summarizeChangesSinceTag(ctx context.Context, repo *git.Repository, tag string, options AnalyzeOptions) string {
+	commits, err := repo.Log(git.LogOptions{
+		Range:       tag + ".." + headOf(repo),
+		Limit:       maxReleaseCommits + 1,
+		WithDiff:    true,
+		MaxDiffSize: maxChangelogDiffSize,
+	})
+	if err != nil {
+		slog.Warn("failed to read the commits since the latest tag", "tag", tag, "error", err)
+		return ""
+	}
+	if len(commits) == 0 {
+		slog.Info(fmt.Sprintf("No commits since %s", tag))
+		return ""
+	}
+	if len(commits) > maxReleaseCommits {
+		slog.Warn(fmt.Sprintf("More than %d commits since %s; summarizing the newest %d", maxReleaseCommits, tag, maxReleaseCommits))
+		commits = commits[:maxReleaseCommits]
+	}
+
+	input := llm.ChangelogInput{
+		ContextSize: options.ContextSize,
+	}
+	for _, c := range commits {
+		input.Commits = append(input.Commits, llm.ChangelogCommit{
+			Hash:    c.Hash,
+			Subject: c.Subject,
+			Body:    c.Body,
+			Diff:    c.Diff,
+		})
+	}
+
+	slog.Info(fmt.Sprintf("🏷  Summarizing %d commits since %s...", len(commits), tag))
+	output, err := a.llmClient.Changelog(ctx, input)
+	if err != nil {
+		slog.Warn("failed to summarize the changes since the latest tag", "tag", tag, "error", err)
+		return ""
+	}
+	return strings.TrimSpace(output.Changelog)
+}
+
+// headOf returns the commit the analysis reads: the revision set with
+// AnalyzeOptions.Rev, or HEAD
+func headOf(repo *git.Repository) string {
+	if rev := repo.Revision(); rev != "" {
+		return rev
+	}
+	return "HEAD"
+}
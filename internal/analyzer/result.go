@@ -0,0 +1,37 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SaveResult writes an analysis result to disk as JSON so documentation can be
+// regenerated later without repeating the analysis
+func SaveResult(path string, result *AnalysisResult) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal analysis result: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write analysis result: %w", err)
+	}
+
+	return nil
+}
+
+// LoadResult reads an analysis result previously written by SaveResult
+func LoadResult(path string) (*AnalysisResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read analysis result: %w", err)
+	}
+
+	var result AnalysisResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse analysis result: %w", err)
+	}
+
+	return &result, nil
+}
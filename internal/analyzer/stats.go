@@ -0,0 +1,123 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/priyupadhyay/repo-sage/pkg/git"
+	"github.com/priyupadhyay/repo-sage/pkg/llm"
+)
+
+// RepoStats summarizes what an analysis of a repository would process
+type RepoStats struct {
+	Files       int                `json:"files"`
+	TotalBytes  int64              `json:"total_bytes"`
+	SourceFiles int                `json:"source_files"` // Files read by detailed analysis without AllFiles
+	Languages   map[string]float64 `json:"languages"`
+
+	Quick    llm.RequestEstimate `json:"quick"`
+	Standard llm.RequestEstimate `json:"standard"`
+	Detailed llm.RequestEstimate `json:"detailed"`
+}
+
+// Stats runs the local passes of an analysis, listing and reading files and
+// splitting them into chunks, and estimates the requests of the quick, standard
+// and detailed modes. It makes no LLM calls.
+func Stats(repoPath string, options AnalyzeOptions) (*RepoStats, error) {
+	repo, err := openRepository(repoPath, options.NoGit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	repo.IgnoreDirs(options.IgnoreDirs...)
+	repo.IncludeSensitive(options.IncludeSensitive...)
+	if err := setFileSource(repo, options.FileSource, options.Untracked); err != nil {
+		return nil, err
+	}
+	if options.Rev != "" {
+		if err := repo.SetRevision(options.Rev); err != nil {
+			return nil, err
+		}
+	}
+	if err := repo.SetScope(options.Subdir); err != nil {
+		return nil, err
+	}
+
+	files, err := repo.ListFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repository files: %w", err)
+	}
+	if options.Since != "" {
+		files, err = filterChangedSince(repo, files, options.Since)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.AffectedSince != "" {
+		files, err = filterAffectedPackages(repo, files, options.AffectedSince)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	languages, err := repoLanguages(repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get language statistics: %w", err)
+	}
+	if options.GroupLanguages {
+		languages = git.GroupLanguages(languages)
+	}
+
+	importantFiles, err := readImportantFiles(repo, files, true, options.DocFiles)
+	if err != nil {
+		return nil, err
+	}
+	toRead := files
+	if !options.AllFiles {
+		toRead = sourceFiles(files, importantFiles)
+	}
+	allContents, err := readFiles(repo, files, options.Concurrency)
+	if err != nil {
+		return nil, err
+	}
+	detailedFiles := make(map[string]string, len(toRead))
+	for _, file := range toRead {
+		detailedFiles[file] = allContents[file]
+	}
+	if err := readContextFiles(repo, options.ContextFiles, importantFiles); err != nil {
+		return nil, err
+	}
+	if err := readContextFiles(repo, options.ContextFiles, detailedFiles); err != nil {
+		return nil, err
+	}
+
+	stats := &RepoStats{
+		Files:       len(files),
+		SourceFiles: len(toRead),
+		Languages:   languages,
+	}
+	for _, content := range allContents {
+		stats.TotalBytes += int64(len(content))
+	}
+
+	input := llm.AnalyzeInput{
+		Languages:       languages,
+		ContextSize:     options.ContextSize,
+		DirStructure:    buildDirStructure(files, options.DirDepth),
+		ChunkSize:       options.ChunkSize,
+		ChunkOverlap:    options.ChunkOverlap,
+		Language:        options.Language,
+		KnownComponents: toLLMComponents(detectComponents(parseGoPackages(repo, files))),
+	}
+	input.Files = importantFiles
+	stats.Standard = llm.EstimateRequests(input)
+
+	input.SummaryOnly = true
+	stats.Quick = llm.EstimateRequests(input)
+	input.SummaryOnly = false
+
+	input.Files = detailedFiles
+	input.IsDetailed = true
+	input.SummarizeFiles = options.FileSummaries
+	stats.Detailed = llm.EstimateRequests(input)
+
+	return stats, nil
+}
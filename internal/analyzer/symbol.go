@@ -0,0 +1,246 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Limits for the line-range fallback used outside Go files
+const (
+	maxSymbolLines    = 200 // Longest block returned for a symbol
+	symbolContextLine = 30  // Lines shown on either side of --line without a symbol
+)
+
+// snippet is a narrowed region of a file
+type snippet struct {
+	Content   string
+	StartLine int
+	EndLine   int
+}
+
+// extractSymbol narrows content to the declaration named symbol, or the one
+// enclosing line when symbol is empty. Go files are parsed; other languages
+// use a brace- or indentation-based line range.
+func extractSymbol(filename, content, symbol string, line int) (*snippet, error) {
+	lines := strings.Split(content, "\n")
+	if line < 0 || line > len(lines) {
+		return nil, fmt.Errorf("line %d is outside the file (1-%d)", line, len(lines))
+	}
+
+	if filepath.Ext(filename) == ".go" {
+		if s, err := extractGoSymbol(filename, content, symbol, line); err == nil {
+			return s, nil
+		} else if symbol != "" {
+			return nil, err
+		}
+		// Unparseable Go with only --line: fall through to the line window
+	}
+
+	if symbol == "" {
+		return lineWindow(lines, line, line), nil
+	}
+
+	start := findDeclarationLine(lines, symbol, line)
+	if start == -1 {
+		return nil, fmt.Errorf("symbol %q not found in %s", symbol, filename)
+	}
+	return blockFrom(lines, start), nil
+}
+
+// extractGoSymbol finds a top-level declaration by name (methods may be given as
+// Type.Method) or by a line it spans, including its doc comment
+func extractGoSymbol(filename, content, symbol string, line int) (*snippet, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+
+	for _, decl := range file.Decls {
+		if !goDeclMatches(fset, decl, symbol, line) {
+			continue
+		}
+
+		start := decl.Pos()
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		}
+
+		from, to := fset.Position(start), fset.Position(decl.End())
+		return &snippet{
+			Content:   content[from.Offset:to.Offset],
+			StartLine: from.Line,
+			EndLine:   to.Line,
+		}, nil
+	}
+
+	if symbol != "" {
+		return nil, fmt.Errorf("symbol %q not found in %s", symbol, filename)
+	}
+	return nil, fmt.Errorf("no declaration at line %d in %s", line, filename)
+}
+
+// goDeclMatches reports whether decl declares symbol, or spans line when symbol is empty
+func goDeclMatches(fset *token.FileSet, decl ast.Decl, symbol string, line int) bool {
+	if symbol == "" {
+		return fset.Position(decl.Pos()).Line <= line && line <= fset.Position(decl.End()).Line
+	}
+
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Name.Name == symbol {
+			return true
+		}
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			return receiverName(d.Recv.List[0].Type)+"."+d.Name.Name == symbol
+		}
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				if s.Name.Name == symbol {
+					return true
+				}
+			case *ast.ValueSpec:
+				for _, name := range s.Names {
+					if name.Name == symbol {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// receiverName returns the type name of a method receiver such as *T or T[K]
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// declKeywords mark lines that likely declare a symbol in common languages
+var declKeywords = regexp.MustCompile(`\b(func|function|def|class|fn|struct|enum|interface|trait|type|impl|module|const|let|var|val|sub|proc)\b`)
+
+// findDeclarationLine returns the 0-based index of the line declaring symbol,
+// preferring lines with a declaration keyword and the first match at or after
+// the given 1-based line. It returns -1 if the symbol never appears.
+func findDeclarationLine(lines []string, symbol string, line int) int {
+	word := regexp.MustCompile(`\b` + regexp.QuoteMeta(symbol) + `\b`)
+	from := 0
+	if line > 0 {
+		from = line - 1
+	}
+
+	fallback := -1
+	for pass := 0; pass < 2; pass++ {
+		for i := range lines {
+			idx := (from + i) % len(lines)
+			if !word.MatchString(lines[idx]) {
+				continue
+			}
+			if pass == 0 && !declKeywords.MatchString(lines[idx]) {
+				if fallback == -1 {
+					fallback = idx
+				}
+				continue
+			}
+			return idx
+		}
+		if fallback != -1 {
+			return fallback
+		}
+	}
+	return -1
+}
+
+// blockFrom returns the block starting at the 0-based start line. Brace-delimited
+// blocks end when the braces balance; otherwise the block ends at the next
+// non-blank line indented no deeper than the start.
+func blockFrom(lines []string, start int) *snippet {
+	indent := indentation(lines[start])
+	depth := 0
+	sawBrace := false
+	end := start
+
+	for i := start; i < len(lines) && i-start < maxSymbolLines; i++ {
+		end = i
+		text := lines[i]
+		depth += strings.Count(text, "{") - strings.Count(text, "}")
+		if strings.Contains(text, "{") {
+			sawBrace = true
+		}
+		if sawBrace && depth <= 0 {
+			break
+		}
+		if !sawBrace && i > start && strings.TrimSpace(text) != "" && indentation(text) <= indent {
+			end = i - 1
+			break
+		}
+	}
+
+	// Drop trailing blank lines from indentation-based blocks
+	for end > start && strings.TrimSpace(lines[end]) == "" {
+		end--
+	}
+
+	return &snippet{
+		Content:   strings.Join(lines[start:end+1], "\n"),
+		StartLine: start + 1,
+		EndLine:   end + 1,
+	}
+}
+
+// lineWindow returns the lines around the 1-based range first..last
+func lineWindow(lines []string, first, last int) *snippet {
+	start := first - 1 - symbolContextLine
+	if start < 0 {
+		start = 0
+	}
+	end := last - 1 + symbolContextLine
+	if end >= len(lines) {
+		end = len(lines) - 1
+	}
+	return &snippet{
+		Content:   strings.Join(lines[start:end+1], "\n"),
+		StartLine: start + 1,
+		EndLine:   end + 1,
+	}
+}
+
+// indentation returns the width of a line's leading whitespace, counting tabs as four
+func indentation(line string) int {
+	width := 0
+	for _, ch := range line {
+		switch ch {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
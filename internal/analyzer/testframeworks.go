@@ -0,0 +1,92 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/priyupadhyay/repo-sage/pkg/git"
+)
+
+// npmDefaultTestScript is part of the placeholder test script written by npm init
+const npmDefaultTestScript = "no test specified"
+
+// detectTestFrameworks finds the test setups of the project from its files and
+// the manifests at the root of the analyzed directory, with the command that
+// most likely runs each: go test for _test.go files, Jest and Vitest from
+// package.json and their config files, pytest and tox, RSpec and Cargo
+func detectTestFrameworks(repo *git.Repository, files []string) []TestFramework {
+	root := repo.Scope()
+	rootFiles := make(map[string]bool)
+	for _, file := range files {
+		if path.Dir(file) == path.Clean(root) {
+			rootFiles[path.Base(file)] = true
+		}
+	}
+	has := func(match func(file string) bool) bool {
+		for _, file := range files {
+			if match(file) {
+				return true
+			}
+		}
+		return false
+	}
+	rootContains := func(name, text string) bool {
+		content, err := repo.ReadFile(path.Join(root, name))
+		return err == nil && strings.Contains(string(content), text)
+	}
+
+	var frameworks []TestFramework
+
+	if has(func(file string) bool { return strings.HasSuffix(file, "_test.go") }) {
+		frameworks = append(frameworks, TestFramework{Name: "Go testing", Command: "go test ./..."})
+	}
+
+	var manifest struct {
+		Scripts         map[string]string `json:"scripts"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if content, err := repo.ReadFile(path.Join(root, "package.json")); err == nil {
+		_ = json.Unmarshal(content, &manifest)
+	}
+	npmTest := manifest.Scripts["test"] != "" && !strings.Contains(manifest.Scripts["test"], npmDefaultTestScript)
+	for _, js := range []struct{ name, command string }{
+		{"Jest", "npx jest"},
+		{"Vitest", "npx vitest run"},
+	} {
+		pkg := strings.ToLower(js.name)
+		_, dep := manifest.Dependencies[pkg]
+		_, devDep := manifest.DevDependencies[pkg]
+		config := has(func(file string) bool { return strings.HasPrefix(path.Base(file), pkg+".config.") })
+		if !dep && !devDep && !config {
+			continue
+		}
+		command := js.command
+		if npmTest {
+			command = "npm test"
+		}
+		frameworks = append(frameworks, TestFramework{Name: js.name, Command: command})
+	}
+
+	if rootFiles["pytest.ini"] || rootFiles["conftest.py"] ||
+		rootContains("pyproject.toml", "[tool.pytest") || rootContains("setup.cfg", "[tool:pytest]") {
+		frameworks = append(frameworks, TestFramework{Name: "pytest", Command: "pytest"})
+	}
+	if rootFiles["tox.ini"] {
+		frameworks = append(frameworks, TestFramework{Name: "tox", Command: "tox"})
+	}
+
+	if rootFiles[".rspec"] || has(func(file string) bool { return strings.HasSuffix(file, "_spec.rb") }) {
+		command := "rspec"
+		if rootFiles["Gemfile"] {
+			command = "bundle exec rspec"
+		}
+		frameworks = append(frameworks, TestFramework{Name: "RSpec", Command: command})
+	}
+
+	if rootFiles["Cargo.toml"] {
+		frameworks = append(frameworks, TestFramework{Name: "Cargo", Command: "cargo test"})
+	}
+	return frameworks
+}
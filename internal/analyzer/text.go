@@ -0,0 +1,35 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// binarySniffLen is how much of a file is checked for NUL bytes, as git does,
+// when deciding whether it is binary
+const binarySniffLen = 8000
+
+// isBinary reports whether content looks like a binary file
+func isBinary(content []byte) bool {
+	if len(content) > binarySniffLen {
+		content = content[:binarySniffLen]
+	}
+	return bytes.IndexByte(content, 0) != -1
+}
+
+// toText converts file content for use in a prompt, replacing invalid UTF-8
+// sequences with U+FFFD so request bodies stay well-formed. It reports false
+// for binary content, which should be skipped.
+func toText(path string, content []byte) (string, bool) {
+	if isBinary(content) {
+		return "", false
+	}
+	if !utf8.Valid(content) {
+		slog.Warn(fmt.Sprintf("%s is not valid UTF-8; replacing invalid bytes", path))
+		return strings.ToValidUTF8(string(content), "\uFFFD"), true
+	}
+	return string(content), true
+}
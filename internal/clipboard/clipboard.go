@@ -0,0 +1,74 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrUnavailable is returned when no clipboard tool is installed
+var ErrUnavailable = errors.New("no clipboard tool found")
+
+// windowsCopy reads standard input as UTF-8, which clip.exe does not, and
+// copies it unchanged
+const windowsCopy = "[Console]::InputEncoding = [Text.Encoding]::UTF8; Set-Clipboard -Value ([Console]::In.ReadToEnd())"
+
+// tools lists the commands that copy their standard input to the clipboard,
+// in order of preference for the current platform
+func tools() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"powershell", "-NoProfile", "-NonInteractive", "-Command", windowsCopy}}
+	}
+	x11 := [][]string{{"xclip", "-selection", "clipboard"}, {"xsel", "--clipboard", "--input"}}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		return append([][]string{{"wl-copy"}}, x11...)
+	}
+	return x11
+}
+
+// command returns the first installed clipboard tool
+func command() ([]string, error) {
+	candidates := tools()
+	names := make([]string, len(candidates))
+	for i, tool := range candidates {
+		if _, err := exec.LookPath(tool[0]); err == nil {
+			return tool, nil
+		}
+		names[i] = tool[0]
+	}
+	return nil, fmt.Errorf("%w: install %s", ErrUnavailable, strings.Join(names, " or "))
+}
+
+// Available reports an error wrapping ErrUnavailable when Write would fail
+// for lack of a clipboard tool, so callers can check before doing slow work
+func Available() error {
+	_, err := command()
+	return err
+}
+
+// Write copies text to the system clipboard
+func Write(text string) error {
+	tool, err := command()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(tool[0], tool[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("failed to copy to the clipboard: %s: %s", tool[0], msg)
+		}
+		return fmt.Errorf("failed to copy to the clipboard: %s: %w", tool[0], err)
+	}
+	return nil
+}
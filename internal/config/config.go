@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 
@@ -10,15 +11,35 @@ import (
 
 // Profile represents an LLM endpoint configuration
 type Profile struct {
-	APIBase string `yaml:"api_base"`
-	APIKey  string `yaml:"api_key"`
-	Model   string `yaml:"model"`
+	APIBase       string `yaml:"api_base"`
+	APIKey        string `yaml:"api_key"`
+	Model         string `yaml:"model"`
+	DefaultOutput string `yaml:"default_output,omitempty"` // Overrides the global default_output
+
+	// RequestsPerMinute paces requests to stay under the provider's rate
+	// limit; 0 sends them as fast as the concurrency allows
+	RequestsPerMinute int `yaml:"requests_per_minute,omitempty"`
+
+	// ExtraBody holds backend-specific request fields, such as seed or stop,
+	// merged into every chat completion request. They take precedence over
+	// the fields repo-sage sets itself.
+	ExtraBody map[string]any `yaml:"extra_body,omitempty"`
 }
 
 // Config represents the main configuration structure
 type Config struct {
 	Profiles       map[string]Profile `yaml:"profiles"`
 	DefaultProfile string             `yaml:"default_profile"`
+	DefaultOutput  string             `yaml:"default_output,omitempty"` // Output path used when --output is not given
+	Language       string             `yaml:"language,omitempty"`       // Natural language used when --lang is not given
+	DocFiles       []string           `yaml:"doc_files,omitempty"`      // Documentation patterns used when --doc-file is not given
+	Languages      map[string]string  `yaml:"languages,omitempty"`      // Extra extension to language mappings, e.g. ".myl": MyLang
+
+	// Footer replaces the "Generated by repo-sage" line of generated documents
+	// and NoFooter leaves the footer out, when --footer and --no-footer are
+	// not given
+	Footer   string `yaml:"footer,omitempty"`
+	NoFooter bool   `yaml:"no_footer,omitempty"`
 }
 
 const (
@@ -26,8 +47,27 @@ const (
 	configFile = "config.yaml"
 )
 
-// GetConfigPath returns the path to the config file
+// EnvConfigPath is the environment variable that points at an alternate config file
+const EnvConfigPath = "REPO_SAGE_CONFIG"
+
+// configPathOverride is the config file set with SetConfigPath
+var configPathOverride string
+
+// SetConfigPath makes LoadConfig and SaveConfig use the given file instead of
+// the default one; an empty path restores the default
+func SetConfigPath(path string) {
+	configPathOverride = path
+}
+
+// GetConfigPath returns the path to the config file: the one set with
+// SetConfigPath, then the one in EnvConfigPath, then ~/.repo-sage/config.yaml
 func GetConfigPath() (string, error) {
+	if configPathOverride != "" {
+		return configPathOverride, nil
+	}
+	if path := os.Getenv(EnvConfigPath); path != "" {
+		return path, nil
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get home directory: %w", err)
@@ -35,7 +75,9 @@ func GetConfigPath() (string, error) {
 	return filepath.Join(home, configDir, configFile), nil
 }
 
-// LoadConfig loads the configuration from disk
+// LoadConfig loads the configuration from disk. Profile values written as
+// env:NAME are kept as they are until Profile.Resolve reads them from the
+// environment variable NAME.
 func LoadConfig() (*Config, error) {
 	configPath, err := GetConfigPath()
 	if err != nil {
@@ -101,6 +143,20 @@ func (c *Config) GetProfile(name string) (Profile, bool) {
 	return profile, exists
 }
 
+// CopyProfile saves a copy of the src profile as dst, which must not exist yet
+func (c *Config) CopyProfile(src, dst string) (Profile, error) {
+	profile, exists := c.Profiles[src]
+	if !exists {
+		return Profile{}, fmt.Errorf("profile %q does not exist", src)
+	}
+	if _, exists := c.Profiles[dst]; exists {
+		return Profile{}, fmt.Errorf("profile %q already exists", dst)
+	}
+	profile.ExtraBody = maps.Clone(profile.ExtraBody)
+	c.Profiles[dst] = profile
+	return profile, nil
+}
+
 // SetDefaultProfile sets the default profile
 func (c *Config) SetDefaultProfile(name string) error {
 	if _, exists := c.Profiles[name]; !exists {
@@ -110,6 +166,15 @@ func (c *Config) SetDefaultProfile(name string) error {
 	return nil
 }
 
+// DefaultOutputFor returns the output path configured for a profile, falling back
+// to the global default_output. It returns an empty string when neither is set.
+func (c *Config) DefaultOutputFor(profile Profile) string {
+	if profile.DefaultOutput != "" {
+		return profile.DefaultOutput
+	}
+	return c.DefaultOutput
+}
+
 // GetDefaultProfile returns the default profile and its name
 func (c *Config) GetDefaultProfile() (Profile, string, error) {
 	if c.DefaultProfile == "" {
@@ -123,3 +188,40 @@ func (c *Config) GetDefaultProfile() (Profile, string, error) {
 
 	return profile, c.DefaultProfile, nil
 }
+
+// RepoConfigFile is the repository-local file that pins a repository's analyze
+// defaults, so everyone running repo-sage on it gets the same settings
+const RepoConfigFile = ".repo-sage.yaml"
+
+// AnalyzeDefaults are the analyze settings a repository can pin. Zero values
+// leave the flag defaults in place, and explicit flags override every field.
+type AnalyzeDefaults struct {
+	Mode       string   `yaml:"mode,omitempty"`        // quick, standard or detailed
+	Output     string   `yaml:"output,omitempty"`      // Output file or directory, relative to the repository root
+	Format     string   `yaml:"format,omitempty"`      // markdown, html, text, json or sarif
+	Context    int      `yaml:"context,omitempty"`     // Context size for AI analysis
+	IgnoreDirs []string `yaml:"ignore_dirs,omitempty"` // Additional directory names to skip
+}
+
+// RepoConfig represents a repository's .repo-sage.yaml
+type RepoConfig struct {
+	Analyze AnalyzeDefaults `yaml:"analyze"`
+}
+
+// LoadRepoConfig loads the .repo-sage.yaml at the root of a repository. It
+// returns an empty configuration if the repository has none.
+func LoadRepoConfig(repoPath string) (*RepoConfig, error) {
+	data, err := os.ReadFile(filepath.Join(repoPath, RepoConfigFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &RepoConfig{}, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", RepoConfigFile, err)
+	}
+
+	var config RepoConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", RepoConfigFile, err)
+	}
+	return &config, nil
+}
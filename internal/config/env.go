@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix marks a profile value read from an environment variable, such as
+// api_key: env:OPENAI_API_KEY, so that secrets need not be stored in the file
+const envPrefix = "env:"
+
+// IsEnvRef reports whether a profile value is an env:NAME reference
+func IsEnvRef(value string) bool {
+	return strings.HasPrefix(value, envPrefix)
+}
+
+// stringFields returns the profile's string fields by their YAML names
+func (p *Profile) stringFields() map[string]*string {
+	return map[string]*string{
+		"api_base":       &p.APIBase,
+		"api_key":        &p.APIKey,
+		"model":          &p.Model,
+		"default_output": &p.DefaultOutput,
+	}
+}
+
+// Resolve returns the profile with the values starting with envPrefix replaced
+// by the environment variables they name. Profiles are only resolved when
+// used, so an unset variable in another profile does not get in the way.
+func (p Profile) Resolve() (Profile, error) {
+	for field, value := range p.stringFields() {
+		name, ok := strings.CutPrefix(*value, envPrefix)
+		if !ok {
+			continue
+		}
+		resolved, set := os.LookupEnv(name)
+		if !set {
+			return Profile{}, fmt.Errorf("%s references environment variable %s, which is not set", field, name)
+		}
+		*value = resolved
+	}
+	return p, nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvReferencesResolvedOnlyWhenUsed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	SetConfigPath(path)
+	t.Cleanup(func() { SetConfigPath("") })
+
+	data := `default_profile: work
+profiles:
+  work:
+    api_base: https://api.example.com/v1
+    api_key: env:REPO_SAGE_TEST_KEY
+    model: gpt-4o
+  other:
+    api_base: env:REPO_SAGE_TEST_UNSET
+    api_key: sk-other
+    model: gpt-4o
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("REPO_SAGE_TEST_KEY", "sk-secret")
+	os.Unsetenv("REPO_SAGE_TEST_UNSET")
+
+	// An unset reference in another profile must not break loading
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	work, _, err := cfg.GetDefaultProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := work.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve(work): %v", err)
+	}
+	if resolved.APIKey != "sk-secret" {
+		t.Errorf("APIKey = %q, want sk-secret", resolved.APIKey)
+	}
+
+	other, _ := cfg.GetProfile("other")
+	if _, err := other.Resolve(); err == nil || !strings.Contains(err.Error(), "REPO_SAGE_TEST_UNSET") {
+		t.Errorf("Resolve(other) error = %v, want one naming REPO_SAGE_TEST_UNSET", err)
+	}
+
+	// Saving writes the references back, never the resolved secrets
+	cfg.AddProfile("new", Profile{APIBase: "http://localhost", APIKey: "sk-new", Model: "m"})
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(saved), "sk-secret") {
+		t.Error("saved config contains the resolved secret")
+	}
+	for _, ref := range []string{"env:REPO_SAGE_TEST_KEY", "env:REPO_SAGE_TEST_UNSET"} {
+		if !strings.Contains(string(saved), ref) {
+			t.Errorf("saved config lost the reference %s", ref)
+		}
+	}
+}
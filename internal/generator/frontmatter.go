@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontmatter is the YAML header that static site generators such as MkDocs
+// and Docusaurus read from the top of Markdown pages
+type frontmatter struct {
+	Title string   `yaml:"title"`
+	Date  string   `yaml:"date"`
+	Tags  []string `yaml:"tags,omitempty"`
+}
+
+// sectionTitles are the page titles of the files written by GenerateFiles,
+// following the repository name
+var sectionTitles = map[string]string{
+	"overview.md":     "Overview",
+	"architecture.md": "Architecture",
+	"components.md":   "Components",
+	"setup.md":        "Setup",
+}
+
+// withFrontmatter prepends the YAML frontmatter to a Markdown page when
+// Options.Frontmatter is set. The title is the repository name, followed by
+// the section for the split files.
+func (g *Generator) withFrontmatter(doc, repoName, file string) (string, error) {
+	if !g.options.Frontmatter {
+		return doc, nil
+	}
+
+	title := repoName
+	if section := sectionTitles[file]; section != "" {
+		title += ": " + section
+	}
+	var header strings.Builder
+	encoder := yaml.NewEncoder(&header)
+	encoder.SetIndent(2)
+	err := encoder.Encode(frontmatter{
+		Title: title,
+		Date:  time.Now().Format("2006-01-02"),
+		Tags:  g.options.FrontmatterTags,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal frontmatter: %w", err)
+	}
+	return "---\n" + header.String() + "---\n\n" + doc, nil
+}
+
+// stripFrontmatter removes a leading YAML frontmatter block, which belongs at
+// the top of a page and not in a section spliced into one
+func stripFrontmatter(doc string) string {
+	if !strings.HasPrefix(doc, "---\n") {
+		return doc
+	}
+	end := strings.Index(doc[len("---\n"):], "\n---\n")
+	if end == -1 {
+		return doc
+	}
+	return doc[len("---\n")+end+len("\n---\n"):]
+}
@@ -0,0 +1,184 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/priyupadhyay/repo-sage/internal/analyzer"
+)
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="generator" content="repo-sage">
+<title>Project Overview: {{.RepoInfo.Name}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 52rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; color: #24292f; }
+code, pre { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; background: #f6f8fa; }
+pre { padding: 1rem; overflow-x: auto; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d0d7de; padding: 0.25rem 0.5rem; text-align: left; vertical-align: top; }
+footer { margin-top: 3rem; color: #57606a; font-size: 0.9rem; }
+</style>
+</head>
+<body>
+<h1>Project Overview: {{.RepoInfo.Name}}</h1>
+{{if .Incomplete}}
+<p><strong>{{emoji "⚠️ "}}Incomplete analysis:</strong> {{.Incomplete}}</p>
+{{end}}
+{{if .Trimmed}}
+<p><strong>Trimmed to fit the context:</strong> {{range $i, $t := .Trimmed}}{{if $i}}, {{end}}{{$t}}{{end}}</p>
+{{end}}
+{{if .RepoInfo.Description}}
+<h2>{{emoji "📌 "}}Purpose</h2>
+{{paragraphs .RepoInfo.Description}}
+{{end}}
+{{if .ReleaseTag}}
+<h2>{{emoji "🏷 "}}Changes since {{.ReleaseTag}}</h2>
+{{if .Changes}}{{paragraphs .Changes}}{{else}}<p>No changes summarized.</p>{{end}}
+{{end}}
+{{if .Architecture}}
+<h2>{{emoji "🧠 "}}Architecture</h2>
+{{paragraphs .Architecture}}
+{{end}}
+{{if .Structure}}
+<h2>{{emoji "🌳 "}}Project Structure</h2>
+<pre>{{.Structure}}</pre>
+{{end}}
+{{if .RepoInfo.Components}}
+<h2>{{emoji "🔍 "}}Components</h2>
+{{range .RepoInfo.Components}}
+<h3>{{.Name}} ({{.Type}})</h3>
+{{paragraphs .Description}}
+<p>Location: <code>{{.Path}}</code></p>
+{{end}}
+{{end}}
+{{if .FileSummaries}}
+<h2>{{emoji "📄 "}}File Summaries</h2>
+<table>
+<tr><th>File</th><th>Summary</th></tr>
+{{range $path, $summary := .FileSummaries}}<tr><td><code>{{$path}}</code></td><td>{{$summary}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if or .RepoInfo.SchemaFiles .RepoInfo.Migrations}}
+<h2>{{emoji "🗄 "}}Data Model</h2>
+{{paragraphs .DataModel}}
+<ul>
+{{if .RepoInfo.SchemaFiles}}<li>Schema files: {{range $i, $f := .RepoInfo.SchemaFiles}}{{if $i}}, {{end}}<code>{{$f}}</code>{{end}}</li>
+{{end}}{{if .RepoInfo.Migrations}}<li>Migrations: {{range $i, $d := .RepoInfo.Migrations}}{{if $i}}, {{end}}<code>{{$d}}</code>{{end}}</li>
+{{end}}</ul>
+{{end}}
+{{if .Subprojects}}
+<h2>{{emoji "🗂 "}}Subprojects</h2>
+{{range .Subprojects}}
+<h3>{{.Name}}</h3>
+<p>Location: <code>{{.Path}}</code> ({{.Manifest}})</p>
+{{paragraphs .Description}}
+{{if .Languages}}<p>Languages: {{languageList .Languages}}</p>
+{{end}}{{if .EntryPoints}}<p>Entry points: {{range $i, $e := .EntryPoints}}{{if $i}}, {{end}}<code>{{$e}}</code>{{end}}</p>
+{{end}}{{if .Components}}<p>Components: {{componentList .Components}}</p>
+{{end}}{{end}}
+{{end}}
+{{if .RepoInfo.EntryPoints}}
+<h2>{{emoji "🚀 "}}Entry Points</h2>
+<ul>
+{{range .RepoInfo.EntryPoints}}<li><code>{{.}}</code>{{if eq . $.RepoInfo.PrimaryEntryPoint}} (primary){{end}}</li>
+{{end}}</ul>
+{{end}}
+{{if .RepoInfo.Dependencies}}
+<h2>{{emoji "📦 "}}Dependencies</h2>
+<ul>
+{{range $dep, $ver := .RepoInfo.Dependencies}}<li>{{$dep}}: {{$ver}}</li>
+{{end}}</ul>
+{{end}}
+{{if .RepoInfo.LicenseFiles}}
+<h2>{{emoji "📜 "}}License</h2>
+<p>{{if .RepoInfo.License}}{{.RepoInfo.License}}. {{end}}See {{range $i, $f := .RepoInfo.LicenseFiles}}{{if $i}}, {{end}}<code>{{$f}}</code>{{end}}.</p>
+{{end}}
+{{if .Setup}}
+<h2>{{emoji "🛠 "}}Setup Instructions</h2>
+{{paragraphs .Setup}}
+{{end}}
+{{if .RepoInfo.TestFrameworks}}
+<h2>{{emoji "🧪 "}}Running Tests</h2>
+<ul>
+{{range .RepoInfo.TestFrameworks}}<li>{{.Name}}: <code>{{.Command}}</code></li>
+{{end}}</ul>
+{{end}}
+{{if .RepoInfo.CI}}
+<h2>{{emoji "⚙️ "}}CI/CD</h2>
+{{paragraphs .CISummary}}
+<ul>
+{{range .RepoInfo.CI}}<li>{{.System}}: {{range $i, $f := .Files}}{{if $i}}, {{end}}<code>{{$f}}</code>{{end}}</li>
+{{end}}</ul>
+{{end}}
+{{if .FlowDiagram}}
+<h2>{{emoji "🌀 "}}Flow Diagram</h2>
+<pre class="mermaid">{{.FlowDiagram}}</pre>
+{{end}}
+{{if .RepoInfo.Languages}}
+<h2>{{emoji "📊 "}}Language Statistics</h2>
+<ul>
+{{range $lang, $pct := .RepoInfo.Languages}}<li>{{$lang}}: {{printf "%.1f%%" $pct}}</li>
+{{end}}</ul>
+{{end}}
+{{if .ChunkAnalyses}}
+<h2>{{emoji "🧩 "}}Appendix: Chunk Analyses</h2>
+{{range $i, $a := .ChunkAnalyses}}
+<h3>Chunk {{inc $i}}</h3>
+{{paragraphs $a}}
+{{end}}
+{{end}}
+{{if not .NoFooter}}<footer>{{if not .AnalyzedAt.IsZero}}Analyzed at {{.AnalyzedAt.Format "2006-01-02T15:04:05Z07:00"}}. {{end}}{{if .Scope}}Scoped to <code>{{.Scope}}/</code>. {{end}}{{if .Revision}}Revision <code>{{.Revision}}</code>. {{end}}{{if .Footer}}{{.Footer}}{{else}}Generated {{emoji "with ❤️ "}}by repo-sage at {{.GeneratedAt}}{{end}}</footer>
+{{end}}</body>
+</html>
+`
+
+// parseHTMLTemplate parses the HTML page template with the shared emoji helper
+func parseHTMLTemplate(emoji func(string) string) (*template.Template, error) {
+	funcs := template.FuncMap{
+		"emoji":      emoji,
+		"paragraphs": paragraphs,
+
+		"languageList":  languageList,
+		"componentList": componentList,
+		"inc":           func(i int) int { return i + 1 },
+	}
+	return template.New("html").Funcs(funcs).Parse(htmlTemplate)
+}
+
+// paragraphs escapes free-form model output and wraps each blank-line separated block in <p>
+func paragraphs(text string) template.HTML {
+	var b strings.Builder
+	for _, block := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		escaped := template.HTMLEscapeString(block)
+		b.WriteString("<p>" + strings.ReplaceAll(escaped, "\n", "<br>\n") + "</p>\n")
+	}
+	return template.HTML(b.String())
+}
+
+// GenerateHTML creates a standalone HTML page from the analysis results
+func (g *Generator) GenerateHTML(result *analyzer.AnalysisResult) (string, error) {
+	var buf bytes.Buffer
+	if err := g.htmlTmpl.Execute(&buf, g.prepare(result)); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// GenerateJSON serializes the analysis results, applying the generator's filters
+func (g *Generator) GenerateJSON(result *analyzer.AnalysisResult) (string, error) {
+	data, err := json.MarshalIndent(g.prepare(result).AnalysisResult, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal analysis result: %w", err)
+	}
+	return string(data), nil
+}
@@ -3,6 +3,7 @@ package generator
 import (
 	"bytes"
 	"fmt"
+	htmltemplate "html/template"
 	"sort"
 	"strings"
 	"text/template"
@@ -11,63 +12,244 @@ import (
 	"github.com/priyupadhyay/repo-sage/internal/analyzer"
 )
 
-const markdownTemplate = `# Project Overview: {{.RepoInfo.Name}}
-
-## 📌 Purpose
+// markdownTemplate defines each documentation section as a named template so
+// the sections can be rendered into one document or split across files
+const markdownTemplate = `{{define "purpose"}}## {{emoji "📌 "}}Purpose
 {{.RepoInfo.Description}}
+{{end}}
+
+{{define "changes"}}{{if .ReleaseTag}}## {{emoji "🏷 "}}Changes since {{.ReleaseTag}}
+{{if .Changes}}{{.Changes}}{{else}}No changes summarized.{{end}}
+
+{{end}}{{end}}
 
-## 🧠 Architecture
+{{define "architecture"}}## {{emoji "🧠 "}}Architecture
 {{.Architecture}}
+{{end}}
+
+{{define "structure"}}{{if .Structure}}## {{emoji "🌳 "}}Project Structure
+` + "```" + `
+{{.Structure}}` + "```" + `
+{{end}}{{end}}
 
-## 🔍 Components
+{{define "components"}}## {{emoji "🔍 "}}Components
 {{range .RepoInfo.Components}}
 ### {{.Name}} ({{.Type}})
 {{.Description}}
 Location: ` + "`" + `{{.Path}}` + "`" + `
 {{end}}
+{{end}}
+
+{{define "files"}}{{if .FileSummaries}}## {{emoji "📄 "}}File Summaries
+| File | Summary |
+|------|---------|
+{{range $path, $summary := .FileSummaries}}| ` + "`" + `{{tableCell $path}}` + "`" + ` | {{tableCell $summary}} |
+{{end}}
+{{end}}{{end}}
+
+{{define "datamodel"}}{{if or .RepoInfo.SchemaFiles .RepoInfo.Migrations}}## {{emoji "🗄 "}}Data Model
+{{if .DataModel}}{{.DataModel}}
+
+{{end}}{{if .RepoInfo.SchemaFiles}}- Schema files: {{codeList .RepoInfo.SchemaFiles}}
+{{end}}{{if .RepoInfo.Migrations}}- Migrations: {{codeList .RepoInfo.Migrations}}
+{{end}}
+{{end}}{{end}}
 
-## 🚀 Entry Points
+{{define "subprojects"}}{{if .Subprojects}}## {{emoji "🗂 "}}Subprojects
+{{range .Subprojects}}### {{.Name}}
+Location: ` + "`" + `{{.Path}}` + "`" + ` ({{.Manifest}})
+{{if .Description}}{{.Description}}
+{{end}}{{if .Languages}}Languages: {{languageList .Languages}}
+{{end}}{{if .EntryPoints}}Entry points: {{codeList .EntryPoints}}
+{{end}}{{if .Components}}Components: {{componentList .Components}}
+{{end}}
+{{end}}{{end}}{{end}}
+
+{{define "entrypoints"}}## {{emoji "🚀 "}}Entry Points
 {{range .RepoInfo.EntryPoints}}
-- ` + "`" + `{{.}}` + "`" + `
+- ` + "`" + `{{.}}` + "`" + `{{if eq . $.RepoInfo.PrimaryEntryPoint}} (primary){{end}}
+{{end}}
 {{end}}
 
-## 📦 Dependencies
+{{define "dependencies"}}## {{emoji "📦 "}}Dependencies
 {{range $dep, $ver := .RepoInfo.Dependencies}}
 - {{$dep}}: {{$ver}}
 {{end}}
+{{end}}
+
+{{define "license"}}{{if .RepoInfo.LicenseFiles}}## {{emoji "📜 "}}License
+{{if .RepoInfo.License}}{{.RepoInfo.License}}. {{end}}See {{codeList .RepoInfo.LicenseFiles}}.
+{{end}}{{end}}
 
-## 🛠 Setup Instructions
+{{define "setup"}}## {{emoji "🛠 "}}Setup Instructions
 {{.Setup}}
+{{end}}
 
-{{if .FlowDiagram}}
-## 🌀 Flow Diagram
+{{define "tests"}}{{if .RepoInfo.TestFrameworks}}## {{emoji "🧪 "}}Running Tests
+{{range .RepoInfo.TestFrameworks}}- {{.Name}}: ` + "`" + `{{.Command}}` + "`" + `
+{{end}}
+{{end}}{{end}}
+
+{{define "ci"}}{{if .RepoInfo.CI}}## {{emoji "⚙️ "}}CI/CD
+{{if .CISummary}}{{.CISummary}}
+
+{{end}}{{range .RepoInfo.CI}}- {{.System}}: {{codeList .Files}}
+{{end}}
+{{end}}{{end}}
+
+{{define "flow"}}{{if .FlowDiagram}}
+## {{emoji "🌀 "}}Flow Diagram
 ` + "```mermaid" + `
 {{.FlowDiagram}}
 ` + "```" + `
 {{end}}
+{{end}}
 
-## 📊 Language Statistics
+{{define "languages"}}## {{emoji "📊 "}}Language Statistics
 {{range $lang, $pct := .RepoInfo.Languages}}
 - {{$lang}}: {{printf "%.1f%%" $pct}}
 {{end}}
+{{end}}
+
+{{define "chunks"}}{{if .ChunkAnalyses}}## {{emoji "🧩 "}}Appendix: Chunk Analyses
+{{range $i, $a := .ChunkAnalyses}}### Chunk {{inc $i}}
+{{$a}}
 
+{{end}}{{end}}{{end}}
+
+{{define "footer"}}` + GeneratedMarker + `{{if not .NoFooter}}
 ---
-Generated with ❤️ by repo-sage at {{.GeneratedAt}}`
+{{if not .AnalyzedAt.IsZero}}Analyzed at {{.AnalyzedAt.Format "2006-01-02T15:04:05Z07:00"}}. {{end}}{{if .Scope}}Scoped to {{.Scope}}/. {{end}}{{if .Revision}}Revision {{.Revision}}. {{end}}{{if .Footer}}{{.Footer}}{{else}}Generated {{emoji "with ❤️ "}}by repo-sage at {{.GeneratedAt}}{{end}}{{end}}{{end}}
+
+{{define "document"}}# Project Overview: {{.RepoInfo.Name}}
+
+{{if .Incomplete}}> {{emoji "⚠️ "}}**Incomplete analysis:** {{.Incomplete}}
+
+{{end}}{{if .Trimmed}}> **Trimmed to fit the context:** {{range $i, $t := .Trimmed}}{{if $i}}, {{end}}{{$t}}{{end}}
+
+{{end}}{{template "purpose" .}}
+{{template "changes" .}}{{template "architecture" .}}
+{{template "structure" .}}
+{{template "components" .}}
+{{template "files" .}}{{template "datamodel" .}}{{template "subprojects" .}}
+{{template "entrypoints" .}}
+{{template "dependencies" .}}
+{{template "license" .}}
+{{template "setup" .}}
+{{template "tests" .}}{{template "ci" .}}{{template "flow" .}}
+{{template "languages" .}}
+{{template "chunks" .}}{{template "footer" .}}{{end}}
+
+{{define "overview.md"}}# {{.RepoInfo.Name}}: Overview
+
+{{template "purpose" .}}
+{{template "changes" .}}{{template "subprojects" .}}
+{{template "entrypoints" .}}
+{{template "dependencies" .}}
+{{template "license" .}}
+{{template "languages" .}}
+{{template "footer" .}}{{end}}
+
+{{define "architecture.md"}}# {{.RepoInfo.Name}}: Architecture
+
+{{template "architecture" .}}
+{{template "structure" .}}
+{{template "flow" .}}
+{{template "footer" .}}{{end}}
+
+{{define "components.md"}}# {{.RepoInfo.Name}}: Components
+
+{{template "components" .}}
+{{template "files" .}}{{template "datamodel" .}}{{template "footer" .}}{{end}}
+
+{{define "setup.md"}}# {{.RepoInfo.Name}}: Setup
+
+{{template "setup" .}}
+{{template "tests" .}}{{template "ci" .}}{{template "footer" .}}{{end}}
+
+{{define "index.md"}}# Project Overview: {{.RepoInfo.Name}}
+
+- [Overview](overview.md)
+- [Architecture](architecture.md)
+- [Components](components.md)
+- [Setup](setup.md)
+
+{{template "footer" .}}{{end}}`
+
+// sectionFiles lists the files written by GenerateFiles, index first
+var sectionFiles = []string{"index.md", "overview.md", "architecture.md", "components.md", "setup.md"}
+
+// Options contains configuration for documentation generation
+type Options struct {
+	NoEmoji        bool     // If true, omit emoji from headings and the footer
+	ComponentTypes []string // If set, only include components of these types (case-insensitive)
+	ShowStructure  bool     // If true, include the directory tree in a Project Structure section
+
+	// Frontmatter starts Markdown pages with YAML frontmatter for MkDocs or
+	// Docusaurus: the page title, the generation date and FrontmatterTags
+	Frontmatter     bool
+	FrontmatterTags []string
+
+	// Footer replaces the "Generated with ❤️ by repo-sage" line of the
+	// footer, and NoFooter leaves the footer out. Markdown and HTML still
+	// carry GeneratedMarker, so IsGenerated recognizes them either way;
+	// text output loses its TextSignature.
+	Footer   string
+	NoFooter bool
+
+	// TemplateDir, if set, holds one template per file written by
+	// GenerateFiles, replacing the built-in split files. File names keep
+	// their relative paths, without a trailing .tmpl.
+	TemplateDir string
+}
 
 // Generator generates documentation from analysis results
 type Generator struct {
-	tmpl *template.Template
+	tmpl     *template.Template
+	htmlTmpl *htmltemplate.Template
+	pages    []page // Loaded from Options.TemplateDir
+	options  Options
 }
 
 // New creates a new Generator instance
-func New() (*Generator, error) {
-	tmpl, err := template.New("markdown").Parse(markdownTemplate)
+func New(options Options) (*Generator, error) {
+	emoji := func(s string) string {
+		if options.NoEmoji {
+			return ""
+		}
+		return s
+	}
+
+	funcs := template.FuncMap{
+		"emoji":         emoji,
+		"languageList":  languageList,
+		"codeList":      codeList,
+		"componentList": componentList,
+		"tableCell":     tableCell,
+		"inc":           func(i int) int { return i + 1 },
+	}
+	tmpl, err := template.New("markdown").Funcs(funcs).Parse(markdownTemplate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template: %w", err)
 	}
 
+	htmlTmpl, err := parseHTMLTemplate(emoji)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML template: %w", err)
+	}
+
+	var pages []page
+	if options.TemplateDir != "" {
+		if pages, err = loadTemplateDir(options.TemplateDir, tmpl); err != nil {
+			return nil, err
+		}
+	}
+
 	return &Generator{
-		tmpl: tmpl,
+		tmpl:     tmpl,
+		htmlTmpl: htmlTmpl,
+		pages:    pages,
+		options:  options,
 	}, nil
 }
 
@@ -75,10 +257,79 @@ func New() (*Generator, error) {
 type templateData struct {
 	*analyzer.AnalysisResult
 	GeneratedAt string
+	Structure   string // Directory tree, set only when Options.ShowStructure is on
+	Footer      string // Replaces the repo-sage line of the footer when set
+	NoFooter    bool   // Leaves the footer out
 }
 
 // Generate creates a Markdown document from the analysis results
 func (g *Generator) Generate(result *analyzer.AnalysisResult) (string, error) {
+	doc, err := g.render("document", g.prepare(result))
+	if err != nil {
+		return "", err
+	}
+	return g.withFrontmatter(doc, result.RepoInfo.Name, "")
+}
+
+// GenerateFiles renders the documentation split by section, returning the
+// contents keyed by file name together with an index linking them. With
+// Options.TemplateDir set, it renders the templates there instead.
+func (g *Generator) GenerateFiles(result *analyzer.AnalysisResult) (map[string]string, error) {
+	data := g.prepare(result)
+
+	if len(g.pages) > 0 {
+		files := make(map[string]string, len(g.pages))
+		for _, p := range g.pages {
+			content, err := renderPage(p, data)
+			if err != nil {
+				return nil, err
+			}
+			if isMarkdown(p.name) {
+				if content, err = g.withFrontmatter(content, result.RepoInfo.Name, p.name); err != nil {
+					return nil, err
+				}
+			}
+			files[p.name] = content
+		}
+		return files, nil
+	}
+
+	files := make(map[string]string, len(sectionFiles))
+	for _, name := range sectionFiles {
+		content, err := g.render(name, data)
+		if err != nil {
+			return nil, err
+		}
+		if content, err = g.withFrontmatter(content, result.RepoInfo.Name, name); err != nil {
+			return nil, err
+		}
+		files[name] = content
+	}
+	return files, nil
+}
+
+// FileNames returns the file names produced by GenerateFiles: the index first
+// and then the sections, or the names of the templates in Options.TemplateDir
+func (g *Generator) FileNames() []string {
+	if len(g.pages) > 0 {
+		names := make([]string, len(g.pages))
+		for i, p := range g.pages {
+			names[i] = p.name
+		}
+		return names
+	}
+	return append([]string(nil), sectionFiles...)
+}
+
+// prepare sorts the analysis results and adds the fields needed by the templates
+func (g *Generator) prepare(result *analyzer.AnalysisResult) templateData {
+	// Filter components by type without modifying the caller's result
+	if len(g.options.ComponentTypes) > 0 {
+		filtered := *result
+		filtered.RepoInfo.Components = filterComponents(result.RepoInfo.Components, g.options.ComponentTypes)
+		result = &filtered
+	}
+
 	// Sort components by type
 	sort.Slice(result.RepoInfo.Components, func(i, j int) bool {
 		if result.RepoInfo.Components[i].Type == result.RepoInfo.Components[j].Type {
@@ -87,9 +338,6 @@ func (g *Generator) Generate(result *analyzer.AnalysisResult) (string, error) {
 		return result.RepoInfo.Components[i].Type < result.RepoInfo.Components[j].Type
 	})
 
-	// Sort entry points
-	sort.Strings(result.RepoInfo.EntryPoints)
-
 	// Sort languages by percentage
 	languages := make([]struct {
 		Name       string
@@ -108,10 +356,77 @@ func (g *Generator) Generate(result *analyzer.AnalysisResult) (string, error) {
 	data := templateData{
 		AnalysisResult: result,
 		GeneratedAt:    time.Now().Format(time.RFC3339),
+		Footer:         g.options.Footer,
+		NoFooter:       g.options.NoFooter,
 	}
+	if g.options.ShowStructure {
+		data.Structure = result.DirStructure
+	}
+	return data
+}
+
+// languageList formats language percentages on one line, most used first, e.g. "Go 80.0%, Shell 20.0%"
+func languageList(languages map[string]float64) string {
+	names := make([]string, 0, len(languages))
+	for lang := range languages {
+		names = append(names, lang)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if languages[names[i]] == languages[names[j]] {
+			return names[i] < names[j]
+		}
+		return languages[names[i]] > languages[names[j]]
+	})
+
+	parts := make([]string, len(names))
+	for i, lang := range names {
+		parts[i] = fmt.Sprintf("%s %.1f%%", lang, languages[lang])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// tableCell keeps free-form text on one line of a Markdown table row, escaping
+// the pipes that would end the cell
+func tableCell(text string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(text), " "), "|", `\|`)
+}
+
+// codeList formats items as comma-separated inline code
+func codeList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = "`" + item + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// componentList formats component names on one line
+func componentList(components []analyzer.Component) string {
+	names := make([]string, len(components))
+	for i, c := range components {
+		names[i] = c.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// filterComponents returns the components whose type matches one of types, ignoring case
+func filterComponents(components []analyzer.Component, types []string) []analyzer.Component {
+	var filtered []analyzer.Component
+	for _, c := range components {
+		for _, t := range types {
+			if strings.EqualFold(c.Type, t) {
+				filtered = append(filtered, c)
+				break
+			}
+		}
+	}
+	return filtered
+}
 
+// render executes a named template and removes empty sections from the output
+func (g *Generator) render(name string, data templateData) (string, error) {
 	var buf bytes.Buffer
-	if err := g.tmpl.Execute(&buf, data); err != nil {
+	if err := g.tmpl.ExecuteTemplate(&buf, name, data); err != nil {
 		return "", fmt.Errorf("failed to execute template: %w", err)
 	}
 
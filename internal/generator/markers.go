@@ -0,0 +1,57 @@
+package generator
+
+import "strings"
+
+// Markers delimit the generated section of a hand-maintained document. Content
+// outside them is preserved when the document is regenerated.
+const (
+	StartMarker = "<!-- repo-sage:start -->"
+	EndMarker   = "<!-- repo-sage:end -->"
+)
+
+// Splice replaces the content between the markers in existing with generated,
+// leaving out the frontmatter of generated. It reports false, leaving the
+// caller to overwrite the file, when existing does not contain a start marker
+// followed by an end marker.
+func Splice(existing, generated string) (string, bool) {
+	start := strings.Index(existing, StartMarker)
+	if start == -1 {
+		return "", false
+	}
+	bodyStart := start + len(StartMarker)
+	end := strings.Index(existing[bodyStart:], EndMarker)
+	if end == -1 {
+		return "", false
+	}
+	bodyEnd := bodyStart + end
+
+	return existing[:bodyStart] + "\n" + strings.Trim(stripFrontmatter(generated), "\n") + "\n" + existing[bodyEnd:], true
+}
+
+// TextSignature ends plain text output, which has no place for a hidden
+// marker, so that it is recognized as generated too
+const TextSignature = "-- Generated by repo-sage"
+
+// GeneratedMarker is written at the end of every Markdown document, ahead of
+// the footer, so that it is recognized whatever the footer says
+const GeneratedMarker = "<!-- repo-sage:generated -->"
+
+// generatedSignatures identify documents repo-sage writes: GeneratedMarker in
+// Markdown, the generator meta tag of HTML, TextSignature, the JSON result's
+// first field and the SARIF tool name, and the default footer of documents
+// written before the marker existed
+var generatedSignatures = []string{
+	GeneratedMarker, `<meta name="generator" content="repo-sage">`, TextSignature,
+	"{\n  \"repo_info\":", `"name": "repo-sage"`, "by repo-sage at ",
+}
+
+// IsGenerated reports whether a document looks like repo-sage output, so that
+// overwriting it loses nothing written by hand
+func IsGenerated(content string) bool {
+	for _, signature := range generatedSignatures {
+		if strings.Contains(content, signature) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/priyupadhyay/repo-sage/internal/analyzer"
+)
+
+// SARIF 2.1.0 document types, limited to the fields repo-sage reports
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// GenerateSARIF reports the documentation findings of the analysis results as a
+// SARIF log, which CI systems such as GitHub code scanning can annotate PRs with
+func (g *Generator) GenerateSARIF(result *analyzer.AnalysisResult) (string, error) {
+	rules := make([]sarifRule, len(analyzer.Rules))
+	for i, r := range analyzer.Rules {
+		rules[i] = sarifRule{ID: r.ID, ShortDescription: sarifMessage{Text: r.Description}}
+	}
+
+	results := []sarifResult{}
+	for _, f := range analyzer.Findings(g.prepare(result).AnalysisResult) {
+		r := sarifResult{
+			RuleID:  f.RuleID,
+			Level:   f.Level,
+			Message: sarifMessage{Text: f.Message},
+		}
+		if f.Path != "" {
+			r.Locations = []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: f.Path},
+				},
+			}}
+		}
+		results = append(results, r)
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "repo-sage",
+				InformationURI: "https://github.com/priyupadhyay/repo-sage",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal SARIF log: %w", err)
+	}
+	return string(data), nil
+}
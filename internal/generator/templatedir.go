@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// templateSuffix is removed from the names of templates in Options.TemplateDir
+// to name the files they produce, so "guide/setup.md.tmpl" writes "guide/setup.md"
+const templateSuffix = ".tmpl"
+
+// page is a user-supplied template producing one file of GenerateFiles
+type page struct {
+	name string // Output file, relative to the output directory
+	tmpl *template.Template
+}
+
+// loadTemplateDir parses every file under dir as a page template. Each page
+// can use the built-in sections, such as {{template "purpose" .}}, and helpers,
+// and may redefine sections without affecting the other pages.
+func loadTemplateDir(dir string, builtin *template.Template) ([]page, error) {
+	var pages []page
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.ToSlash(rel), templateSuffix)
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read template: %w", err)
+		}
+		set, err := builtin.Clone()
+		if err != nil {
+			return fmt.Errorf("failed to copy the built-in templates: %w", err)
+		}
+		tmpl, err := set.New(name).Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("failed to parse template %s: %w", rel, err)
+		}
+		pages = append(pages, page{name: name, tmpl: tmpl})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load templates from %s: %w", dir, err)
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no templates found in %s", dir)
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].name < pages[j].name })
+	return pages, nil
+}
+
+// renderPage executes a page template. Unlike the built-in files, empty
+// sections are left in place, since the layout is the user's.
+func renderPage(p page, data templateData) (string, error) {
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute template %s: %w", p.name, err)
+	}
+	return buf.String(), nil
+}
+
+// isMarkdown reports whether a page is a Markdown file, which gets frontmatter
+func isMarkdown(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".md" || ext == ".markdown" || ext == ".mdx"
+}
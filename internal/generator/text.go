@@ -0,0 +1,27 @@
+package generator
+
+import (
+	"strings"
+
+	"github.com/priyupadhyay/repo-sage/internal/analyzer"
+)
+
+// GenerateText returns the model's description, architecture and setup as
+// plain paragraphs, without headings, emoji or other template decoration, for
+// piping into other tools or posting to chat. A final TextSignature line marks
+// the text as generated unless NoFooter is set.
+func (g *Generator) GenerateText(result *analyzer.AnalysisResult) string {
+	var sections []string
+	for _, text := range []string{result.RepoInfo.Description, result.Architecture, result.Setup} {
+		if text = strings.TrimSpace(text); text != "" {
+			sections = append(sections, text)
+		}
+	}
+	if !g.options.NoFooter {
+		sections = append(sections, TextSignature)
+	}
+	if len(sections) == 0 {
+		return ""
+	}
+	return strings.Join(sections, "\n\n") + "\n"
+}
@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"strings"
+	"unicode"
+)
+
+// StripEmoji removes emoji (and the spacing that follows them) from s
+func StripEmoji(s string) string {
+	var b strings.Builder
+	skipSpace := false
+	for _, r := range s {
+		if isEmoji(r) {
+			skipSpace = true
+			continue
+		}
+		if skipSpace && r == ' ' {
+			continue
+		}
+		skipSpace = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isEmoji reports whether r is an emoji or an emoji presentation modifier
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // pictographs, emoticons, transport, etc.
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // miscellaneous symbols and dingbats
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // arrows and stars such as ⭐
+		return true
+	case r == 0x200D || r == 0xFE0F || r == 0x20E3: // joiners and variation selectors
+		return true
+	}
+	return unicode.Is(unicode.Variation_Selector, r)
+}
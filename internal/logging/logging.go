@@ -0,0 +1,196 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ParseLevel converts a level name (debug, info, warn, error) to a slog.Level
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q (expected debug, info, warn or error)", name)
+}
+
+// Options configures the default logger
+type Options struct {
+	Level   string
+	NoEmoji bool // If true, strip emoji from messages and progress output
+	JSON    bool // If true, write logs and progress as JSON lines for other tools
+
+	// NoProgress hides progress indicators and info messages, leaving warnings,
+	// errors and any debug output
+	NoProgress bool
+}
+
+var (
+	noEmoji  bool
+	jsonMode bool
+)
+
+// Setup installs the default slog logger writing to stderr, human-friendly or as JSON lines
+func Setup(options Options) error {
+	lvl, err := ParseLevel(options.Level)
+	if err != nil {
+		return err
+	}
+	noEmoji = options.NoEmoji
+	jsonMode = options.JSON
+
+	var handler slog.Handler
+	if options.JSON {
+		handler = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: lvl, ReplaceAttr: stripMessageEmoji})
+	} else {
+		h := NewHandler(os.Stderr, lvl)
+		h.noEmoji = options.NoEmoji
+		handler = h
+	}
+	if options.NoProgress {
+		handler = quietHandler{handler}
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// stripMessageEmoji removes the emoji that decorate messages for terminals
+// from the message of JSON records, which other tools read
+func stripMessageEmoji(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.MessageKey {
+		return slog.String(slog.MessageKey, strings.TrimSpace(StripEmoji(a.Value.String())))
+	}
+	return a
+}
+
+// quietHandler drops info records, which carry progress, and passes the
+// others through. Progress indicators check Enabled(slog.LevelInfo), so they
+// stay hidden as well.
+type quietHandler struct {
+	slog.Handler
+}
+
+// Enabled reports whether the wrapped handler handles the level, never for info
+func (h quietHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level != slog.LevelInfo && h.Handler.Enabled(ctx, level)
+}
+
+// Handle passes records other than info ones to the wrapped handler
+func (h quietHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level == slog.LevelInfo {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a quiet handler wrapping the handler with the attributes
+func (h quietHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return quietHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a quiet handler wrapping the handler with the group
+func (h quietHandler) WithGroup(name string) slog.Handler {
+	return quietHandler{h.Handler.WithGroup(name)}
+}
+
+// Enabled reports whether the default logger emits records at the given level
+func Enabled(level slog.Level) bool {
+	return slog.Default().Enabled(context.Background(), level)
+}
+
+// Handler is a slog.Handler that writes plain messages for info records and
+// prefixes other levels, so progress output stays readable in a terminal
+type Handler struct {
+	mu      *sync.Mutex
+	w       io.Writer
+	level   slog.Leveler
+	attrs   []slog.Attr
+	group   string
+	noEmoji bool
+}
+
+// NewHandler creates a new Handler writing records at or above level to w
+func NewHandler(w io.Writer, level slog.Leveler) *Handler {
+	return &Handler{
+		mu:    &sync.Mutex{},
+		w:     w,
+		level: level,
+	}
+}
+
+// Enabled reports whether the handler handles records at the given level
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle formats and writes a single record
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	if r.Level != slog.LevelInfo {
+		b.WriteString(r.Level.String())
+		b.WriteString(": ")
+	}
+	if h.noEmoji {
+		b.WriteString(StripEmoji(r.Message))
+	} else {
+		b.WriteString(r.Message)
+	}
+
+	for _, a := range h.attrs {
+		writeAttr(&b, h.group, a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&b, h.group, a)
+		return true
+	})
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	progressMu.Lock()
+	defer progressMu.Unlock()
+
+	clearActive()
+	_, err := io.WriteString(h.w, b.String())
+	redrawActive()
+	return err
+}
+
+// WithAttrs returns a handler that includes the given attributes in every record
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &clone
+}
+
+// WithGroup returns a handler that prefixes attribute keys with the group name
+func (h *Handler) WithGroup(name string) slog.Handler {
+	clone := *h
+	if clone.group != "" {
+		clone.group += "."
+	}
+	clone.group += name
+	return &clone
+}
+
+func writeAttr(b *strings.Builder, group string, a slog.Attr) {
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	key := a.Key
+	if group != "" {
+		key = group + "." + key
+	}
+	fmt.Fprintf(b, " %s=%v", key, a.Value.Resolve())
+}
@@ -0,0 +1,26 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestJSONMessagesWithoutEmoji(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: stripMessageEmoji}))
+	logger.Info("✨ Analysis complete! Documentation saved to docs/ 📄", "file", "🚀 kept")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid JSON log line %q: %v", buf.String(), err)
+	}
+	if got, want := record["msg"], "Analysis complete! Documentation saved to docs/"; got != want {
+		t.Errorf("msg = %q, want %q", got, want)
+	}
+	// Only the message is decoration; attribute values are data
+	if got := record["file"]; got != "🚀 kept" {
+		t.Errorf("file = %q, want the attribute unchanged", got)
+	}
+}
@@ -0,0 +1,211 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	barWidth         = 30
+	lineOutputPeriod = 2 * time.Second
+)
+
+var (
+	progressMu sync.Mutex
+	active     *Progress
+)
+
+// Progress renders the progress of a single stage to stderr. On a terminal it
+// draws an in-place bar; when stderr is piped it prints periodic status lines;
+// in JSON mode every update is logged as a "progress" record.
+type Progress struct {
+	w          io.Writer
+	label      string
+	total      int
+	current    int
+	detail     string // Shown after the counter, e.g. an ETA
+	tty        bool
+	json       bool
+	enabled    bool
+	lastLine   time.Time
+	lastDecile int
+	printed    int // step count at the last printed line, when not a terminal
+	done       bool
+}
+
+// NewProgress starts a progress indicator for a stage with the given number of steps
+func NewProgress(label string, total int) *Progress {
+	if noEmoji || jsonMode {
+		label = strings.TrimSpace(StripEmoji(label))
+	}
+	p := &Progress{
+		w:          os.Stderr,
+		label:      label,
+		total:      total,
+		tty:        isTerminal(os.Stderr) && !jsonMode,
+		json:       jsonMode,
+		enabled:    Enabled(slog.LevelInfo),
+		lastDecile: -1,
+		printed:    -1,
+	}
+
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	active = p
+	p.render()
+	return p
+}
+
+// Set updates the number of completed steps
+func (p *Progress) Set(current int) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	if p.done {
+		return
+	}
+	p.current = current
+	p.render()
+}
+
+// SetDetail sets text shown after the step counter, such as "ETA 1m20s".
+// Non-terminal output shows it on the next printed line.
+func (p *Progress) SetDetail(detail string) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	if p.done {
+		return
+	}
+	p.detail = detail
+	if p.enabled && p.tty {
+		p.draw()
+	}
+}
+
+// Increment advances the progress by one step
+func (p *Progress) Increment() {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	if p.done {
+		return
+	}
+	p.current++
+	p.render()
+}
+
+// Finish marks the stage as complete and releases the terminal line
+func (p *Progress) Finish() {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	if p.done {
+		return
+	}
+	p.done = true
+	if active == p {
+		active = nil
+	}
+	if !p.enabled {
+		return
+	}
+	if p.tty {
+		p.draw()
+		fmt.Fprintln(p.w)
+		return
+	}
+	if p.json {
+		return
+	}
+	if p.printed != p.current {
+		p.printLine()
+	}
+}
+
+// render must be called with progressMu held
+func (p *Progress) render() {
+	if !p.enabled {
+		return
+	}
+	if p.tty {
+		p.draw()
+		return
+	}
+	if p.json {
+		if p.printed != p.current {
+			p.printed = p.current
+			attrs := []any{"stage", p.label, "current", p.current, "total", p.total}
+			if p.detail != "" {
+				attrs = append(attrs, "detail", p.detail)
+			}
+			slog.Info("progress", attrs...)
+		}
+		return
+	}
+
+	// Without a terminal, print a line for every 10% step or after a quiet period
+	decile := 0
+	if p.total > 0 {
+		decile = p.current * 10 / p.total
+	}
+	if decile == p.lastDecile && time.Since(p.lastLine) < lineOutputPeriod {
+		return
+	}
+	p.lastDecile = decile
+	p.printLine()
+}
+
+func (p *Progress) printLine() {
+	p.lastLine = time.Now()
+	p.printed = p.current
+	fmt.Fprintf(p.w, "%s %s\n", p.label, p.counter())
+}
+
+func (p *Progress) draw() {
+	filled := 0
+	if p.total > 0 {
+		filled = p.current * barWidth / p.total
+	}
+	if filled > barWidth {
+		filled = barWidth
+	}
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+	fmt.Fprintf(p.w, "\r\033[K%s [%s] %s", p.label, bar, p.counter())
+}
+
+func (p *Progress) counter() string {
+	counter := fmt.Sprintf("%d", p.current)
+	if p.total > 0 {
+		counter = fmt.Sprintf("%d/%d", p.current, p.total)
+	}
+	if p.detail != "" {
+		counter += " " + p.detail
+	}
+	return counter
+}
+
+// clearActive erases an in-place progress bar before a log line is written.
+// It must be called with progressMu held.
+func clearActive() {
+	if active != nil && active.enabled && active.tty {
+		fmt.Fprint(active.w, "\r\033[K")
+	}
+}
+
+// redrawActive restores an in-place progress bar after a log line is written.
+// It must be called with progressMu held.
+func redrawActive() {
+	if active != nil && active.enabled && active.tty {
+		active.draw()
+	}
+}
+
+// isTerminal reports whether f is a terminal, rather than another character
+// device such as /dev/null
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Mode selects how model output is written to the terminal
+type Mode string
+
+const (
+	// Markdown renders headings, emphasis and code with ANSI styling
+	Markdown Mode = "markdown"
+	// Plain writes the response unchanged
+	Plain Mode = "plain"
+)
+
+const (
+	ansiReset     = "\033[0m"
+	ansiBold      = "\033[1m"
+	ansiDim       = "\033[2m"
+	ansiItalic    = "\033[3m"
+	ansiUnderline = "\033[4m"
+	ansiCyan      = "\033[36m"
+	ansiMagenta   = "\033[35m"
+	ansiYellow    = "\033[33m"
+)
+
+var (
+	headingRe    = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	listRe       = regexp.MustCompile(`^(\s*)([-*+]|\d+\.)\s+(.*)$`)
+	inlineCodeRe = regexp.MustCompile("`([^`]+)`")
+	boldRe       = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicRe     = regexp.MustCompile(`(^|[^*])\*([^*\s][^*]*)\*`)
+)
+
+// ParseMode validates a --render flag value
+func ParseMode(name string) (Mode, error) {
+	switch Mode(strings.ToLower(name)) {
+	case Markdown:
+		return Markdown, nil
+	case Plain:
+		return Plain, nil
+	}
+	return "", fmt.Errorf("unknown render mode %q (expected markdown or plain)", name)
+}
+
+// Write prints text to stdout using the given mode, falling back to plain
+// output when stdout is not a terminal
+func Write(text string, mode Mode) {
+	if mode == Markdown && IsTerminal(os.Stdout) {
+		text = Terminal(text)
+	}
+	fmt.Println(text)
+}
+
+// Terminal renders Markdown text with ANSI styling for display in a terminal
+func Terminal(text string) string {
+	var b strings.Builder
+	inCode := false
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			lang := strings.TrimPrefix(trimmed, "```")
+			if inCode && lang != "" {
+				b.WriteString(ansiDim + "  " + lang + ansiReset + "\n")
+			}
+			continue
+		}
+
+		if inCode {
+			b.WriteString("  " + ansiCyan + line + ansiReset + "\n")
+			continue
+		}
+
+		if m := headingRe.FindStringSubmatch(line); m != nil {
+			style := ansiBold + ansiMagenta
+			if len(m[1]) == 1 {
+				style += ansiUnderline
+			}
+			b.WriteString(style + m[2] + ansiReset + "\n")
+			continue
+		}
+
+		if m := listRe.FindStringSubmatch(line); m != nil {
+			bullet := "•"
+			if strings.HasSuffix(m[2], ".") {
+				bullet = m[2]
+			}
+			b.WriteString(m[1] + ansiYellow + bullet + ansiReset + " " + inline(m[3]) + "\n")
+			continue
+		}
+
+		b.WriteString(inline(line) + "\n")
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// inline applies styling to code spans and emphasis within a line
+func inline(line string) string {
+	line = inlineCodeRe.ReplaceAllString(line, ansiCyan+"$1"+ansiReset)
+	line = boldRe.ReplaceAllString(line, ansiBold+"$1$2"+ansiReset)
+	line = italicRe.ReplaceAllString(line, "$1"+ansiItalic+"$2"+ansiReset)
+	return line
+}
+
+// IsTerminal reports whether f is a terminal. Other character devices, such
+// as the /dev/null that CI jobs often get as standard input, are not.
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,140 @@
+package git
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileSource selects how ListFiles finds the files of the working tree
+type FileSource string
+
+const (
+	// FileSourceGit lists the files tracked in git's index, so gitignored
+	// build output and new untracked files are left out. Contents are still
+	// read from the working tree.
+	FileSourceGit FileSource = "git"
+
+	// FileSourceWorktree walks every file on disk, including untracked and
+	// gitignored ones, skipping only ignored directories and sensitive files
+	FileSourceWorktree FileSource = "worktree"
+)
+
+// ParseFileSource returns the file source with the given name, "git" or "worktree"
+func ParseFileSource(name string) (FileSource, error) {
+	switch source := FileSource(name); source {
+	case FileSourceGit, FileSourceWorktree:
+		return source, nil
+	}
+	return "", fmt.Errorf("unknown file source %q (expected git or worktree)", name)
+}
+
+// SetFileSource selects how ListFiles finds files. FileSourceGit, the default,
+// falls back to walking the working tree when the repository has no index yet
+// or git cannot list it.
+func (r *Repository) SetFileSource(source FileSource) {
+	r.fileSource = source
+}
+
+// SetIncludeUntracked makes FileSourceGit also list files that git does not
+// track but does not ignore either, such as new files not yet committed.
+// FileSourceWorktree already lists them.
+func (r *Repository) SetIncludeUntracked(include bool) {
+	r.includeUntracked = include
+}
+
+// listTrackedFiles lists the files in git's index that exist in the working
+// tree, skipping submodules, ignored directories and sensitive files
+func (r *Repository) listTrackedFiles() ([]string, error) {
+	out, err := r.runGit("ls-files", "-z", "--stage")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range strings.Split(out, "\x00") {
+		// Entries are "<mode> <object> <stage>\t<path>"; submodules have mode 160000
+		meta, file, ok := strings.Cut(entry, "\t")
+		if !ok || strings.HasPrefix(meta, "160000 ") {
+			continue
+		}
+		if r.inIgnoredDir(file) || r.isSensitive(path.Base(file)) {
+			continue
+		}
+		// Tracked files deleted from the working tree cannot be read
+		if _, err := os.Lstat(filepath.Join(r.Path, file)); err != nil {
+			continue
+		}
+		if len(files) > 0 && files[len(files)-1] == file {
+			continue // Unmerged files appear once per conflict stage
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// listUntrackedFiles lists the files of the working tree that git neither
+// tracks nor ignores, skipping ignored directories and sensitive files
+func (r *Repository) listUntrackedFiles() ([]string, error) {
+	out, err := r.runGit("ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, file := range strings.Split(out, "\x00") {
+		// Nested repositories are listed as a directory ending in a slash
+		if file == "" || strings.HasSuffix(file, "/") {
+			continue
+		}
+		if r.inIgnoredDir(file) || r.isSensitive(path.Base(file)) {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// hasIndex reports whether git has an index to list files from; a freshly
+// initialized repository has none until the first file is staged
+func (r *Repository) hasIndex() bool {
+	out, err := r.runGit("rev-parse", "--git-path", "index")
+	if err != nil {
+		return false
+	}
+	index := strings.TrimSpace(out)
+	if !filepath.IsAbs(index) {
+		index = filepath.Join(r.Path, index)
+	}
+	_, err = os.Stat(index)
+	return err == nil
+}
+
+// listWorkingFiles lists the files of the working tree from the configured source
+func (r *Repository) listWorkingFiles() ([]string, error) {
+	if r.fileSource == FileSourceWorktree || r.noGit {
+		return r.listWorkingTree()
+	}
+	if !r.hasIndex() {
+		slog.Debug("no git index, listing the working tree instead")
+		return r.listWorkingTree()
+	}
+	files, err := r.listTrackedFiles()
+	if err != nil {
+		slog.Debug("failed to list tracked files, listing the working tree instead", "error", err)
+		return r.listWorkingTree()
+	}
+	if r.includeUntracked {
+		untracked, err := r.listUntrackedFiles()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list untracked files: %w", err)
+		}
+		files = append(files, untracked...)
+		sort.Strings(files)
+	}
+	return files, nil
+}
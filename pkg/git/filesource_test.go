@@ -0,0 +1,72 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// newTestRepo creates a git repository holding files, staging those in tracked
+func newTestRepo(t *testing.T, files map[string]string, tracked ...string) *Repository {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"init", "-q"}, append([]string{"add"}, tracked...)} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	repo, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestIncludeUntracked(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		".gitignore":     "*.log\n",
+		"main.go":        "package main\n",
+		"new.go":         "package main\n",
+		"pkg/new/new.go": "package new\n",
+		"debug.log":      "ignored\n",
+		"node_modules/x": "ignored directory\n",
+	}, ".gitignore", "main.go")
+
+	tests := []struct {
+		name      string
+		untracked bool
+		want      []string
+	}{
+		{"tracked only", false, []string{".gitignore", "main.go"}},
+		{"with untracked", true, []string{".gitignore", "main.go", "new.go", "pkg/new/new.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo.SetIncludeUntracked(tt.untracked)
+			got, err := repo.ListFiles()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ListFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// customLanguages maps lowercase extensions, such as ".myl", to the languages
+// registered for them, which take precedence over the built-in ones
+var (
+	customLanguagesMu sync.RWMutex
+	customLanguages   = make(map[string]string)
+)
+
+// RegisterLanguages adds extension to language mappings, such as ".myl" to
+// "MyLang", so files of internal languages are counted in language statistics
+// and analyzed as source files. Extensions are matched case-insensitively and
+// may be compound, such as ".tmpl.html"; the longest matching one wins.
+func RegisterLanguages(languages map[string]string) error {
+	customLanguagesMu.Lock()
+	defer customLanguagesMu.Unlock()
+
+	for ext, language := range languages {
+		if len(ext) < 2 || !strings.HasPrefix(ext, ".") {
+			return fmt.Errorf("invalid extension %q for language %q (expected a dot and a suffix such as .myl)", ext, language)
+		}
+		if strings.TrimSpace(language) == "" {
+			return fmt.Errorf("missing language for extension %q", ext)
+		}
+		customLanguages[strings.ToLower(ext)] = language
+	}
+	return nil
+}
+
+// customLanguage returns the registered language with the longest extension
+// matching the file name, or an empty string if none matches
+func customLanguage(filename string) string {
+	customLanguagesMu.RLock()
+	defer customLanguagesMu.RUnlock()
+
+	name := strings.ToLower(filename)
+	language, matched := "", 0
+	for ext, lang := range customLanguages {
+		if len(ext) > matched && strings.HasSuffix(name, ext) {
+			language, matched = lang, len(ext)
+		}
+	}
+	return language
+}
+
+// languageGroups rolls up languages that are usually one language in practice,
+// such as React components and the JavaScript or TypeScript around them
+var languageGroups = map[string]string{
+	"JavaScript":       "JavaScript/TypeScript",
+	"TypeScript":       "JavaScript/TypeScript",
+	"React":            "JavaScript/TypeScript",
+	"React/TypeScript": "JavaScript/TypeScript",
+	"C":                "C/C++",
+	"C++":              "C/C++",
+	"C/C++ Header":     "C/C++",
+	"SASS":             "CSS",
+}
+
+// GroupLanguages returns language statistics with related languages merged, so
+// that JavaScript, TypeScript and React count as "JavaScript/TypeScript" and C,
+// C++ and their headers as "C/C++". Other languages are kept as they are.
+func GroupLanguages(languages map[string]float64) map[string]float64 {
+	grouped := make(map[string]float64, len(languages))
+	for language, share := range languages {
+		if group, ok := languageGroups[language]; ok {
+			language = group
+		}
+		grouped[language] += share
+	}
+	return grouped
+}
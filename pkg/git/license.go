@@ -0,0 +1,120 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// licenseFilePattern matches the names of license files at the repository root,
+// including per-license files of dual-licensed projects such as LICENSE-MIT
+var licenseFilePattern = regexp.MustCompile(`(?i)^(licen[cs]e|copying)([-_.][\w.-]*)?$`)
+
+// spdxIdentifierPattern matches an explicit SPDX-License-Identifier tag
+var spdxIdentifierPattern = regexp.MustCompile(`SPDX-License-Identifier:\s*([\w.+-]+)`)
+
+// licenseSignatures identify licenses by phrases of their text, such as their
+// headline, checked in order so more specific licenses come before those whose
+// text they contain
+var licenseSignatures = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license version 3"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license version 2.1"}},
+	{"LGPL-2.0", []string{"gnu library general public license version 2"}},
+	{"GPL-3.0", []string{"gnu general public license version 3"}},
+	{"GPL-2.0", []string{"gnu general public license version 2"}},
+	{"Apache-2.0", []string{"apache license version 2.0"}},
+	{"MPL-2.0", []string{"mozilla public license version 2.0"}},
+	{"EPL-2.0", []string{"eclipse public license - v 2.0"}},
+	{"BSL-1.0", []string{"boost software license"}},
+	{"CC0-1.0", []string{"cc0 1.0 universal"}},
+	{"Unlicense", []string{"free and unencumbered software released into the public domain"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+}
+
+// License identifies the license of the repository from the LICENSE, LICENCE or
+// COPYING files at its root. It returns the SPDX identifier, such as "MIT",
+// joined with " OR " for dual-licensed projects, and the files it read. The
+// identifier is empty when there is no license file or its text is not recognized.
+func (r *Repository) License() (spdx string, files []string, err error) {
+	names, err := r.rootFiles()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var ids []string
+	for _, name := range names {
+		if !licenseFilePattern.MatchString(name) {
+			continue
+		}
+		content, err := r.ReadFile(name)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		files = append(files, name)
+		if id := DetectLicense(string(content)); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return strings.Join(ids, " OR "), files, nil
+}
+
+// DetectLicense returns the SPDX identifier of a license text, preferring an
+// explicit SPDX-License-Identifier tag, or an empty string if it is not recognized
+func DetectLicense(text string) string {
+	if m := spdxIdentifierPattern.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+
+	normalized := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	for _, signature := range licenseSignatures {
+		matched := true
+		for _, phrase := range signature.phrases {
+			if !strings.Contains(normalized, phrase) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return signature.id
+		}
+	}
+	return ""
+}
+
+// rootFiles lists the names of the files directly in the repository root, in
+// the working tree or at the current revision, regardless of any scope
+func (r *Repository) rootFiles() ([]string, error) {
+	var names []string
+	if r.rev != "" {
+		out, err := r.runGit("ls-tree", "-z", r.rev)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files at %s: %w", r.rev, err)
+		}
+		for _, entry := range strings.Split(out, "\x00") {
+			if meta, name, ok := strings.Cut(entry, "\t"); ok && strings.Contains(meta, " blob ") {
+				names = append(names, name)
+			}
+		}
+	} else {
+		entries, err := os.ReadDir(r.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read repository root: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,140 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Commit represents a single commit in the repository history
+type Commit struct {
+	Hash    string
+	Author  string
+	Date    time.Time
+	Subject string
+	Body    string
+	Diff    string
+}
+
+const (
+	fieldSep  = "\x1f"
+	recordSep = "\x1e"
+)
+
+// LogOptions controls which commits are returned by Log
+type LogOptions struct {
+	Range       string // Revision range such as "v1.0.0..HEAD"; empty means HEAD
+	Limit       int    // Maximum number of commits; 0 means no limit
+	WithDiff    bool   // If true, populate Commit.Diff
+	MaxDiffSize int    // Truncate each diff to this many bytes; 0 means no limit
+}
+
+// Log returns commits from the repository history, newest first
+func (r *Repository) Log(options LogOptions) ([]Commit, error) {
+	args := []string{"log", "--format=%H" + fieldSep + "%an" + fieldSep + "%aI" + fieldSep + "%s" + fieldSep + "%b" + recordSep}
+	if options.Limit > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", options.Limit))
+	}
+	if options.Range != "" {
+		args = append(args, options.Range)
+	}
+	args = append(args, "--")
+
+	out, err := r.runGit(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit log: %w", err)
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(out, recordSep) {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, fieldSep, 5)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected git log output: %q", record)
+		}
+
+		date, err := time.Parse(time.RFC3339, fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse commit date %q: %w", fields[2], err)
+		}
+
+		commit := Commit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Date:    date,
+			Subject: fields[3],
+			Body:    strings.TrimSpace(fields[4]),
+		}
+
+		if options.WithDiff {
+			diff, err := r.runGit("show", "--format=", "--patch", "--stat", commit.Hash)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read diff for %s: %w", commit.Hash, err)
+			}
+			if options.MaxDiffSize > 0 && len(diff) > options.MaxDiffSize {
+				diff = diff[:options.MaxDiffSize] + "\n... (diff truncated)"
+			}
+			commit.Diff = diff
+		}
+
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
+
+// ChangedSince returns the files changed since a commit (compared with HEAD, or
+// the revision set with SetRevision) or since a date understood by git, such as
+// "2024-01-31" or "2 weeks ago"
+func (r *Repository) ChangedSince(since string) ([]string, error) {
+	var out string
+	if _, err := r.runGit("rev-parse", "--verify", "--quiet", since+"^{commit}"); err == nil {
+		out, err = r.runGit("diff", "--name-only", since, r.head(), "--")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files changed since %s: %w", since, err)
+		}
+	} else {
+		out, err = r.runGit("log", "--since="+since, "--name-only", "--format=", r.head(), "--")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files changed since %s: %w", since, err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		files = append(files, line)
+	}
+
+	return files, nil
+}
+
+// runGit executes a git command in the repository and returns its standard output
+func (r *Repository) runGit(args ...string) (string, error) {
+	if r.noGit {
+		return "", fmt.Errorf("git %s: %w", args[0], ErrNoGit)
+	}
+	cmd := exec.Command("git", append([]string{"-C", r.Path}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return stdout.String(), nil
+}
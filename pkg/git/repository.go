@@ -1,17 +1,102 @@
 package git
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
+// DefaultIgnoredDirs lists dependency, build output and cache directories that
+// are skipped when listing files, across common ecosystems
+var DefaultIgnoredDirs = []string{
+	// JavaScript
+	"node_modules", "dist", ".next", ".nuxt", "out", "coverage",
+	// Python
+	".venv", "venv", "env", "__pycache__", ".mypy_cache", ".pytest_cache", ".tox",
+	// Go, PHP, Ruby
+	"vendor",
+	// Rust, Java, Kotlin
+	"target", "build", ".gradle",
+	// .NET
+	"bin", "obj",
+	// iOS
+	"Pods",
+}
+
+// DefaultSensitiveFiles lists glob patterns, matched against file names, of
+// files that may hold credentials. They are never listed, whether or not they
+// are gitignored, unless a pattern is removed with IncludeSensitive.
+var DefaultSensitiveFiles = []string{
+	".env", ".env.*", "*.env",
+	"*.pem", "*.key", "*.p12", "*.pfx", "*.jks", "*.keystore",
+	"id_rsa", "id_dsa", "id_ecdsa", "id_ed25519",
+	"secrets.yaml", "secrets.yml", "secrets.json", "*.secret", "*.secrets",
+	"credentials", "credentials.json",
+	".npmrc", ".pypirc", ".netrc", ".htpasswd",
+	"*.tfstate", "*.tfstate.backup",
+}
+
+// sensitiveTemplates are example files matched by DefaultSensitiveFiles that
+// document configuration without holding real values
+var sensitiveTemplates = map[string]bool{
+	".env.example":  true,
+	".env.sample":   true,
+	".env.template": true,
+	".env.dist":     true,
+}
+
+// lockFiles are generated dependency lock files, which cost tokens without describing the code
+var lockFiles = map[string]bool{
+	"package-lock.json":   true,
+	"npm-shrinkwrap.json": true,
+	"yarn.lock":           true,
+	"pnpm-lock.yaml":      true,
+	"bun.lockb":           true,
+	"go.sum":              true,
+	"Cargo.lock":          true,
+	"Gemfile.lock":        true,
+	"composer.lock":       true,
+	"poetry.lock":         true,
+	"Pipfile.lock":        true,
+	"uv.lock":             true,
+	"mix.lock":            true,
+	"pubspec.lock":        true,
+	"Podfile.lock":        true,
+	"packages.lock.json":  true,
+}
+
+// IsLockFile reports whether a file is a dependency lock file such as go.sum or yarn.lock
+func IsLockFile(path string) bool {
+	return lockFiles[filepath.Base(path)]
+}
+
+// IsSourceFile reports whether a file is in a recognized language and is not a lock file
+func IsSourceFile(path string) bool {
+	return detectLanguage(path) != "" && !IsLockFile(path)
+}
+
 // Repository represents a Git repository
 type Repository struct {
 	Path string
+
+	ignoredDirs       map[string]bool
+	sensitivePatterns []string
+	rev               string     // Commit read instead of the working tree; see SetRevision
+	scope             string     // Directory ListFiles is limited to; see SetScope
+	fileSource        FileSource // How ListFiles finds working tree files; see SetFileSource
+	includeUntracked  bool       // Add untracked files to the tracked ones; see SetIncludeUntracked
+	noGit             bool       // Opened with NewDirectory, so git commands are unavailable
 }
 
+// ErrNotRepository is returned by New for a directory without a .git directory
+var ErrNotRepository = errors.New("not a git repository")
+
+// ErrNoGit is returned by operations that need git, such as reading a
+// revision or the commit log, on a directory opened with NewDirectory
+var ErrNoGit = errors.New("not available without a git repository")
+
 // New creates a new Repository instance
 func New(path string) (*Repository, error) {
 	absPath, err := filepath.Abs(path)
@@ -27,17 +112,102 @@ func New(path string) (*Repository, error) {
 	// Check if it's a Git repository
 	gitDir := filepath.Join(absPath, ".git")
 	if _, err := os.Stat(gitDir); err != nil {
-		return nil, fmt.Errorf("not a git repository: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrNotRepository, err)
+	}
+
+	repo := &Repository{
+		Path:              absPath,
+		ignoredDirs:       make(map[string]bool),
+		sensitivePatterns: append([]string(nil), DefaultSensitiveFiles...),
+		fileSource:        FileSourceGit,
 	}
+	repo.IgnoreDirs(DefaultIgnoredDirs...)
+	return repo, nil
+}
 
-	return &Repository{
-		Path: absPath,
-	}, nil
+// NewDirectory opens a plain source directory, such as an extracted tarball,
+// that need not be a git repository. ListFiles walks it like FileSourceWorktree,
+// and operations that need git, such as SetRevision and Log, return ErrNoGit.
+func NewDirectory(path string) (*Repository, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("directory does not exist: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", absPath)
+	}
+
+	repo := &Repository{
+		Path:              absPath,
+		ignoredDirs:       make(map[string]bool),
+		sensitivePatterns: append([]string(nil), DefaultSensitiveFiles...),
+		fileSource:        FileSourceWorktree,
+		noGit:             true,
+	}
+	repo.IgnoreDirs(DefaultIgnoredDirs...)
+	return repo, nil
+}
+
+// IgnoreDirs adds directory names to skip when listing files, in addition to DefaultIgnoredDirs
+func (r *Repository) IgnoreDirs(names ...string) {
+	for _, name := range names {
+		r.ignoredDirs[name] = true
+	}
+}
+
+// IncludeSensitive removes patterns from the sensitive-file denylist so matching
+// files are listed again. Patterns must match entries of DefaultSensitiveFiles exactly.
+func (r *Repository) IncludeSensitive(patterns ...string) {
+	for _, include := range patterns {
+		kept := r.sensitivePatterns[:0]
+		for _, p := range r.sensitivePatterns {
+			if p != include {
+				kept = append(kept, p)
+			}
+		}
+		r.sensitivePatterns = kept
+	}
 }
 
-// ListFiles returns all tracked files in the repository
+// isSensitive reports whether a file name matches the sensitive-file denylist
+func (r *Repository) isSensitive(name string) bool {
+	if sensitiveTemplates[name] {
+		return false
+	}
+	for _, pattern := range r.sensitivePatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ListFiles returns the files of the repository as forward-slash separated
+// paths relative to the root, on every platform: those at the revision when one
+// is set, and otherwise those found by the file source. With a scope set, only
+// files under that directory are returned, still relative to the root.
 func (r *Repository) ListFiles() ([]string, error) {
 	var files []string
+	var err error
+	if r.rev != "" {
+		files, err = r.listFilesAtRev()
+	} else {
+		files, err = r.listWorkingFiles()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.inScope(files), nil
+}
+
+// listWorkingTree walks the working tree, skipping ignored directories and sensitive files
+func (r *Repository) listWorkingTree() ([]string, error) {
+	var files []string
 
 	err := filepath.Walk(r.Path, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -49,14 +219,19 @@ func (r *Repository) ListFiles() ([]string, error) {
 			return filepath.SkipDir
 		}
 
+		// Skip common dependency directories
+		if info.IsDir() && path != r.Path && r.isDependencyDir(path) {
+			return filepath.SkipDir
+		}
+
 		// Skip directories
 		if info.IsDir() {
 			return nil
 		}
 
-		// Skip common dependency directories
-		if isInDependencyDir(path) {
-			return filepath.SkipDir
+		// Never expose files that may hold credentials
+		if r.isSensitive(info.Name()) {
+			return nil
 		}
 
 		relPath, err := filepath.Rel(r.Path, path)
@@ -64,7 +239,7 @@ func (r *Repository) ListFiles() ([]string, error) {
 			return err
 		}
 
-		files = append(files, relPath)
+		files = append(files, filepath.ToSlash(relPath))
 		return nil
 	})
 
@@ -75,29 +250,18 @@ func (r *Repository) ListFiles() ([]string, error) {
 	return files, nil
 }
 
-// isInDependencyDir checks if the path is in a common dependency directory
-func isInDependencyDir(path string) bool {
-	deps := []string{
-		"node_modules",
-		"vendor",
-		"dist",
-		"build",
-		".venv",
-		"venv",
-		"env",
-	}
-
-	for _, dep := range deps {
-		if filepath.Base(path) == dep {
-			return true
-		}
-	}
-
-	return false
+// isDependencyDir checks if the path is a dependency, build output or cache directory
+func (r *Repository) isDependencyDir(path string) bool {
+	return r.ignoredDirs[filepath.Base(path)]
 }
 
-// ReadFile reads the contents of a file in the repository
+// ReadFile reads the contents of a file in the repository, given a
+// repository-relative path with either separator
 func (r *Repository) ReadFile(path string) ([]byte, error) {
+	if r.rev != "" {
+		return r.ReadFileAtRev(r.rev, filepath.ToSlash(path))
+	}
+
 	fullPath := filepath.Join(r.Path, path)
 	content, err := os.ReadFile(fullPath)
 	if err != nil {
@@ -112,7 +276,12 @@ func (r *Repository) GetLanguages() (map[string]float64, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to list files: %w", err)
 	}
+	return r.LanguagesOf(files)
+}
 
+// LanguagesOf returns the usage percentages of languages among the given files,
+// such as the files of one subproject
+func (r *Repository) LanguagesOf(files []string) (map[string]float64, error) {
 	// Count bytes per language
 	langBytes := make(map[string]int64)
 	totalBytes := int64(0)
@@ -144,8 +313,18 @@ func (r *Repository) GetLanguages() (map[string]float64, error) {
 	return result, nil
 }
 
-// detectLanguage returns the programming language based on file extension
+// detectLanguage returns the programming language based on file extension,
+// preferring languages added with RegisterLanguages
 func detectLanguage(filename string) string {
+	if language := customLanguage(filename); language != "" {
+		return language
+	}
+
+	// Compound extensions that filepath.Ext would split
+	if strings.HasSuffix(strings.ToLower(filename), ".gradle.kts") {
+		return "Gradle"
+	}
+
 	ext := strings.ToLower(filepath.Ext(filename))
 	switch ext {
 	case ".go":
@@ -178,7 +357,7 @@ func detectLanguage(filename string) string {
 		return "C#"
 	case ".swift":
 		return "Swift"
-	case ".kt":
+	case ".kt", ".kts":
 		return "Kotlin"
 	case ".scala":
 		return "Scala"
@@ -214,6 +393,32 @@ func detectLanguage(filename string) string {
 		return "Protocol Buffer"
 	case ".graphql", ".gql":
 		return "GraphQL"
+	case ".tf", ".tfvars", ".hcl":
+		return "Terraform"
+	case ".toml":
+		return "TOML"
+	case ".ini", ".cfg":
+		return "INI"
+	case ".gradle":
+		return "Gradle"
+	case ".r":
+		return "R"
+	case ".jl":
+		return "Julia"
+	case ".dart":
+		return "Dart"
+	case ".ex", ".exs":
+		return "Elixir"
+	case ".clj", ".cljs", ".cljc", ".edn":
+		return "Clojure"
+	case ".lua":
+		return "Lua"
+	case ".pl", ".pm":
+		return "Perl"
+	case ".m", ".mm":
+		return "Objective-C"
+	case ".zig":
+		return "Zig"
 	}
 
 	return ""
@@ -0,0 +1,44 @@
+package git
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"infra/main.tf", "Terraform"},
+		{"prod.tfvars", "Terraform"},
+		{"config.hcl", "Terraform"},
+		{"Cargo.toml", "TOML"},
+		{"setup.ini", "INI"},
+		{"setup.cfg", "INI"},
+		{"analysis.r", "R"},
+		{"analysis.R", "R"},
+		{"solver.jl", "Julia"},
+		{"lib/main.dart", "Dart"},
+		{"lib/app.ex", "Elixir"},
+		{"test/app_test.exs", "Elixir"},
+		{"src/core.clj", "Clojure"},
+		{"src/ui.cljs", "Clojure"},
+		{"src/shared.cljc", "Clojure"},
+		{"deps.edn", "Clojure"},
+		{"init.lua", "Lua"},
+		{"script.pl", "Perl"},
+		{"Module.pm", "Perl"},
+		{"AppDelegate.m", "Objective-C"},
+		{"Bridge.mm", "Objective-C"},
+		{"build.gradle", "Gradle"},
+		{"build.gradle.kts", "Gradle"},
+		{"settings.kts", "Kotlin"},
+		{"build.zig", "Zig"},
+		{"main.go", "Go"},
+		{"data.unknownext", ""},
+		{"Makefile", ""},
+	}
+	for _, tt := range tests {
+		if got := detectLanguage(tt.filename); got != tt.want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// SetRevision makes ListFiles and ReadFile, and everything built on them, read
+// the tree of a commit instead of the working tree. rev may be anything git
+// resolves to a commit, such as a tag or branch name.
+func (r *Repository) SetRevision(rev string) error {
+	if r.noGit {
+		return fmt.Errorf("cannot read revision %q: %w", rev, ErrNoGit)
+	}
+	out, err := r.runGit("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("unknown revision %q", rev)
+	}
+	r.rev = strings.TrimSpace(out)
+	return nil
+}
+
+// Revision returns the commit set with SetRevision, or an empty string when
+// the working tree is read
+func (r *Repository) Revision() string {
+	return r.rev
+}
+
+// ReadFileAtRev reads the contents of a file as of a revision
+func (r *Repository) ReadFileAtRev(rev, file string) ([]byte, error) {
+	out, err := r.runGit("cat-file", "blob", rev+":"+file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s at %s: %w", file, rev, err)
+	}
+	return []byte(out), nil
+}
+
+// LookupFileAtRev reads a file as of a revision like ReadFileAtRev, but
+// reports false instead of an error when the revision does not contain the
+// file, such as one added since. An unknown revision is still an error.
+func (r *Repository) LookupFileAtRev(rev, file string) ([]byte, bool, error) {
+	if r.noGit {
+		return nil, false, fmt.Errorf("cannot read revision %q: %w", rev, ErrNoGit)
+	}
+	if _, err := r.runGit("rev-parse", "--verify", "--quiet", rev+"^{commit}"); err != nil {
+		return nil, false, fmt.Errorf("unknown revision %q", rev)
+	}
+	if _, err := r.runGit("cat-file", "-e", rev+":"+file); err != nil {
+		return nil, false, nil
+	}
+	content, err := r.ReadFileAtRev(rev, file)
+	if err != nil {
+		return nil, false, err
+	}
+	return content, true, nil
+}
+
+// listFilesAtRev lists the files in the tree of the current revision, skipping
+// the same directories and sensitive files as the working-tree walk
+func (r *Repository) listFilesAtRev() ([]string, error) {
+	out, err := r.runGit("ls-tree", "-r", "-z", r.rev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files at %s: %w", r.rev, err)
+	}
+
+	var files []string
+	for _, entry := range strings.Split(out, "\x00") {
+		// Entries are "<mode> <type> <object>\t<path>"; submodules have type commit
+		meta, file, ok := strings.Cut(entry, "\t")
+		if !ok || !strings.Contains(meta, " blob ") {
+			continue
+		}
+		if r.inIgnoredDir(file) || r.isSensitive(path.Base(file)) {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// inIgnoredDir reports whether any directory of a repository-relative path is ignored
+func (r *Repository) inIgnoredDir(file string) bool {
+	dirs := strings.Split(file, "/")
+	for _, dir := range dirs[:len(dirs)-1] {
+		if r.ignoredDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// head returns the commit that reads and diffs compare against: the revision
+// set with SetRevision, or HEAD
+func (r *Repository) head() string {
+	if r.rev != "" {
+		return r.rev
+	}
+	return "HEAD"
+}
@@ -0,0 +1,62 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SetScope limits ListFiles, and everything built on it, to a subdirectory.
+// dir may be relative to the repository root or absolute; git commands still
+// run at the root, and listed paths stay relative to it. Call it after
+// SetRevision so the directory is looked up in the right tree.
+func (r *Repository) SetScope(dir string) error {
+	if filepath.IsAbs(dir) {
+		rel, err := filepath.Rel(r.Path, dir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", dir, err)
+		}
+		dir = rel
+	}
+	scope := path.Clean(filepath.ToSlash(dir))
+	if scope == ".." || strings.HasPrefix(scope, "../") {
+		return fmt.Errorf("%s is outside the repository", dir)
+	}
+	if scope == "." {
+		r.scope = ""
+		return nil
+	}
+
+	if r.rev != "" {
+		out, err := r.runGit("cat-file", "-t", r.rev+":"+scope)
+		if err != nil || strings.TrimSpace(out) != "tree" {
+			return fmt.Errorf("%s is not a directory at %s", dir, r.rev)
+		}
+	} else if info, err := os.Stat(filepath.Join(r.Path, scope)); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s is not a directory of the repository", dir)
+	}
+	r.scope = scope
+	return nil
+}
+
+// Scope returns the directory set with SetScope, or an empty string when the
+// whole repository is listed
+func (r *Repository) Scope() string {
+	return r.scope
+}
+
+// inScope returns the files under the scope directory
+func (r *Repository) inScope(files []string) []string {
+	if r.scope == "" {
+		return files
+	}
+	var scoped []string
+	for _, file := range files {
+		if strings.HasPrefix(file, r.scope+"/") {
+			scoped = append(scoped, file)
+		}
+	}
+	return scoped
+}
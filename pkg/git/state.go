@@ -0,0 +1,60 @@
+package git
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// StateKey identifies the current contents of the repository: the HEAD commit,
+// the size and modification time of every changed or untracked file, and the
+// skipped directories, sensitive files and scope that limit the listed files.
+// It changes whenever a commit is made or the working tree is edited, so it can
+// key caches of derived data. With a revision set, only that commit and the
+// listing options matter.
+func (r *Repository) StateKey() (string, error) {
+	head, err := r.runGit("rev-parse", r.head())
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", r.head(), err)
+	}
+	status := ""
+	if r.rev == "" {
+		status, err = r.runGit("status", "--porcelain=v1", "-z", "--untracked-files=all")
+		if err != nil {
+			return "", fmt.Errorf("failed to read working tree status: %w", err)
+		}
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", strings.TrimSpace(head))
+
+	// Status lines don't change when an already modified file is edited again,
+	// so include each changed file's size and modification time
+	for _, entry := range strings.Split(status, "\x00") {
+		if len(entry) < 4 {
+			continue
+		}
+		path := entry[3:]
+		fmt.Fprintf(h, "%s", entry)
+		if info, err := os.Stat(filepath.Join(r.Path, path)); err == nil {
+			fmt.Fprintf(h, " %d %d", info.Size(), info.ModTime().UnixNano())
+		}
+		h.Write([]byte{'\n'})
+	}
+
+	ignored := make([]string, 0, len(r.ignoredDirs))
+	for name := range r.ignoredDirs {
+		ignored = append(ignored, name)
+	}
+	sort.Strings(ignored)
+	fmt.Fprintf(h, "%s\n", strings.Join(ignored, "/"))
+	fmt.Fprintf(h, "%s\n", strings.Join(r.sensitivePatterns, "/"))
+	fmt.Fprintf(h, "%s\n", r.scope)
+	fmt.Fprintf(h, "%s\n", r.fileSource)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,19 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LatestTag returns the most recent tag reachable from HEAD, or from the
+// revision set with SetRevision
+func (r *Repository) LatestTag() (string, error) {
+	if r.noGit {
+		return "", fmt.Errorf("cannot look up tags: %w", ErrNoGit)
+	}
+	out, err := r.runGit("describe", "--tags", "--abbrev=0", r.head())
+	if err != nil {
+		return "", fmt.Errorf("no tag found before %s", r.head())
+	}
+	return strings.TrimSpace(out), nil
+}
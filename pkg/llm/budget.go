@@ -0,0 +1,53 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// ErrTokenBudget is returned, wrapped, when a request would exceed the token budget of a run
+var ErrTokenBudget = errors.New("token budget exceeded")
+
+// chatUsage is the token accounting reported by OpenAI-compatible endpoints
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// messageTokens estimates the prompt tokens of a conversation
+func messageTokens(messages []chatMessage) int {
+	tokens := 0
+	for _, m := range messages {
+		tokens += EstimateTokens(m.Content)
+	}
+	return tokens
+}
+
+// checkBudget refuses a request whose estimated prompt would take the run past
+// its token budget. Concurrent requests may overshoot by the size of those in flight.
+func (c *openAIClient) checkBudget(messages []chatMessage) error {
+	if c.tokenBudget <= 0 {
+		return nil
+	}
+	used := c.tokensUsed.Load()
+	next := int64(messageTokens(messages))
+	if used+next > int64(c.tokenBudget) {
+		return fmt.Errorf("%w: %d of %d tokens used, the next request needs about %d", ErrTokenBudget, used, c.tokenBudget, next)
+	}
+	return nil
+}
+
+// recordUsage adds a request's tokens to the run total, estimating them when
+// the endpoint does not report usage
+func (c *openAIClient) recordUsage(messages []chatMessage, content string, usage *chatUsage) {
+	tokens := 0
+	if usage != nil && usage.TotalTokens > 0 {
+		tokens = usage.TotalTokens
+	} else {
+		tokens = messageTokens(messages) + EstimateTokens(content)
+	}
+	total := c.tokensUsed.Add(int64(tokens))
+	slog.Debug("token usage", "request", tokens, "total", total, "reported", usage != nil)
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Template for the system message of a conversation about a codebase
+const chatSystemPrompt = `You are a helpful AI assistant answering questions about a codebase.
+
+Repository Overview:
+%s
+
+Answer from the files provided with each question. Refer to files by their
+paths, and say so when the provided files do not contain the answer instead
+of guessing.`
+
+// Template for each question of a conversation
+const chatPrompt = `Relevant Files:
+%s
+
+Question: %s`
+
+// Chat answers a question about the codebase. Earlier turns are sent as
+// plain questions and answers; only the current question carries file contents.
+func (c *openAIClient) Chat(ctx context.Context, input ChatInput) (*ChatOutput, error) {
+	system := fmt.Sprintf(chatSystemPrompt, input.Overview) + languageInstruction(input.Language)
+	messages := []chatMessage{{Role: "system", Content: withSuffix(system, input.PromptSuffix)}}
+	for _, turn := range input.History {
+		messages = append(messages,
+			chatMessage{Role: "user", Content: turn.Question},
+			chatMessage{Role: "assistant", Content: turn.Answer},
+		)
+	}
+	messages = append(messages, chatMessage{
+		Role:    "user",
+		Content: fmt.Sprintf(chatPrompt, formatChatFiles(input.Files), input.Question),
+	})
+
+	response, err := c.complete(ctx, messages, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ChatOutput{Answer: response}, nil
+}
+
+// formatChatFiles renders retrieved files in name order
+func formatChatFiles(files map[string]string) string {
+	if len(files) == 0 {
+		return "(no files matched the question)\n"
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n--- %s ---\n%s\n", name, files[name])
+	}
+	return b.String()
+}
@@ -3,15 +3,74 @@ package llm
 import (
 	"context"
 	"fmt"
+	"sync"
 )
 
 // Client defines the interface for LLM interactions
 type Client interface {
-	// Analyze generates an analysis of the provided code context
+	// Analyze generates an analysis of the provided code context. The progress
+	// callback may be nil and is never invoked concurrently.
 	Analyze(ctx context.Context, input AnalyzeInput, progress ProgressCallback) (*AnalyzeOutput, error)
 
-	// ExplainFile generates an explanation of a specific file
-	ExplainFile(ctx context.Context, input ExplainInput) (*ExplainOutput, error)
+	// ExplainFile generates an explanation of a specific file. The stream
+	// callback may be nil; otherwise it receives the explanation as it is generated.
+	ExplainFile(ctx context.Context, input ExplainInput, stream StreamCallback) (*ExplainOutput, error)
+
+	// ExplainDiff explains how a file changed between two versions and why the
+	// change matters. The stream callback may be nil, as for ExplainFile.
+	ExplainDiff(ctx context.Context, input ExplainDiffInput, stream StreamCallback) (*ExplainOutput, error)
+
+	// IdentifyComponents lists the main components of the codebase as structured
+	// data. A non-nil found receives each component as soon as the model has
+	// written it, for progressive display.
+	IdentifyComponents(ctx context.Context, input AnalyzeInput, found ComponentCallback) ([]Component, error)
+
+	// Changelog generates grouped release notes from a list of commits
+	Changelog(ctx context.Context, input ChangelogInput) (*ChangelogOutput, error)
+
+	// Chat answers a question about the codebase, continuing a conversation
+	Chat(ctx context.Context, input ChatInput) (*ChatOutput, error)
+
+	// SummarizeCI describes how the project is built and deployed from its CI/CD configuration
+	SummarizeCI(ctx context.Context, input CIInput) (*CIOutput, error)
+
+	// SummarizeDataModel describes the entities and relationships of the project's database schema
+	SummarizeDataModel(ctx context.Context, input DataModelInput) (*DataModelOutput, error)
+
+	// ListModels returns the model identifiers served by the endpoint
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// ProgressCallback is called to report progress during analysis.
+//
+// Clients never invoke a callback concurrently: invocations are serialized even
+// when work such as chunk analysis runs in parallel, so callbacks may update
+// shared state or write to the terminal without their own locking. Callbacks
+// should return quickly because they block other progress reports.
+//
+// After each chunk of a detailed analysis, the "Estimated time remaining" stage
+// carries the expected remaining duration in response, formatted like "1m20s".
+type ProgressCallback func(stage string, current, total int, response string)
+
+// StreamCallback receives a response piece by piece as the model generates it.
+// Concatenated, the pieces make up the complete response.
+type StreamCallback func(text string)
+
+// ComponentCallback receives each component identified by the model, in the
+// order they are written
+type ComponentCallback func(component Component)
+
+// serializeProgress wraps a callback so that concurrent calls are delivered one at a time
+func serializeProgress(progress ProgressCallback) ProgressCallback {
+	if progress == nil {
+		return nil
+	}
+	var mu sync.Mutex
+	return func(stage string, current, total int, response string) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress(stage, current, total, response)
+	}
 }
 
 // AnalyzeInput contains the input for code analysis
@@ -19,24 +78,83 @@ type AnalyzeInput struct {
 	Files        map[string]string // filename -> content
 	Languages    map[string]float64
 	ContextSize  int
-	DirStructure string // Tree-like directory structure
-	IsDetailed   bool   // Whether to perform detailed analysis
+	DirStructure string     // Tree-like directory structure
+	IsDetailed   bool       // Whether to perform detailed analysis
+	SummaryOnly  bool       // Whether to ask only for a one-paragraph description, in exactly one request
+	Checkpoint   Checkpoint // Optional store for per-chunk results of detailed analysis
+	ChunkSize    int        // Characters per chunk in detailed analysis; 0 derives it from ContextSize
+	ChunkOverlap int        // Characters of the previous chunk repeated at the start of the next
+	Concurrency  int        // Chunks analyzed in parallel; 0 uses DefaultConcurrency
+	Language     string     // Natural language for the prose of the response; empty for English
+	PromptSuffix string     // Extra instructions appended to every prompt, e.g. "Focus on security"
+
+	// SummarizeFiles makes a detailed analysis also summarize each significant
+	// file in one sentence, in batches after the summary
+	SummarizeFiles bool
+
+	// KnownComponents were found by static analysis; the model is asked to
+	// describe these by name instead of inventing its own
+	KnownComponents []Component
+
+	// Subprojects lists the subprojects of a monorepo, e.g. "web (package.json)",
+	// so the model describes how they fit together
+	Subprojects []string
+
+	// Importers counts, for files whose imports are known, how many packages
+	// of the repository import them; detailed analysis reads widely imported
+	// files first
+	Importers map[string]int
+}
+
+// Checkpoint persists per-chunk analysis results so an interrupted detailed
+// analysis can resume without repeating successful requests. Keys identify a
+// chunk together with the language and instructions it was analyzed with.
+type Checkpoint interface {
+	// Load returns the stored response for a key, if any
+	Load(key string) (string, bool)
+
+	// Save stores the response for a key
+	Save(key string, response string) error
 }
 
 // AnalyzeOutput contains the analysis results
 type AnalyzeOutput struct {
-	Description  string
-	Architecture string
-	Components   []Component
-	Setup        string
-	FlowDiagram  string
+	Description   string
+	Architecture  string
+	Components    []Component
+	Setup         string
+	FlowDiagram   string
+	Chunks        []ChunkAnalysis   // Per-chunk analyses of a detailed analysis, in chunk order
+	FileSummaries map[string]string // One-sentence summaries of significant files, keyed by path, when requested
+	Incomplete    string            // Why the analysis stopped early, if it did
+	Trimmed       []string          // Key files shortened or left out to fit the context, e.g. "go.sum (left out)"
+}
+
+// ChunkAnalysis is the prompt and response for one chunk of a detailed analysis
+type ChunkAnalysis struct {
+	Prompt   string
+	Response string
 }
 
 // ExplainInput contains the input for file explanation
 type ExplainInput struct {
-	Filename    string
-	Content     string
-	ContextSize int
+	Filename     string
+	Content      string
+	ContextSize  int
+	Language     string // Natural language for the explanation; empty for English
+	PromptSuffix string // Extra instructions appended to the prompt
+}
+
+// ExplainDiffInput contains both versions of a file whose change to explain
+type ExplainDiffInput struct {
+	Filename     string
+	Base         string // Label of the old version, e.g. "main"
+	Head         string // Label of the new version, e.g. "working tree"
+	Old          string // Content at Base; empty when the file is new
+	New          string // Content at Head
+	ContextSize  int
+	Language     string // Natural language for the explanation; empty for English
+	PromptSuffix string // Extra instructions appended to the prompt
 }
 
 // ExplainOutput contains the file explanation
@@ -46,21 +164,105 @@ type ExplainOutput struct {
 	Components  []string
 }
 
+// ChangelogInput contains the input for changelog generation
+type ChangelogInput struct {
+	Commits     []ChangelogCommit
+	ContextSize int
+}
+
+// ChangelogCommit is a single commit to summarize
+type ChangelogCommit struct {
+	Hash    string
+	Subject string
+	Body    string
+	Diff    string // Optional, possibly truncated patch
+}
+
+// ChangelogOutput contains the generated changelog
+type ChangelogOutput struct {
+	Changelog string
+}
+
+// ChatInput contains a question about the codebase and the conversation so far
+type ChatInput struct {
+	Question     string
+	Overview     string            // Summary of the repository, such as its languages and directory tree
+	Files        map[string]string // Files retrieved as relevant to the question: filename -> content
+	History      []ChatTurn        // Earlier questions and answers, oldest first
+	Language     string            // Natural language for the answer; empty for English
+	PromptSuffix string            // Extra instructions appended to the system prompt
+}
+
+// ChatTurn is one question and answer of a conversation
+type ChatTurn struct {
+	Question string
+	Answer   string
+}
+
+// ChatOutput contains the answer to a chat question
+type ChatOutput struct {
+	Answer string
+}
+
+// CIInput contains the CI/CD configuration files of a repository
+type CIInput struct {
+	Files        map[string]string // filename -> content
+	Language     string            // Natural language for the summary; empty for English
+	PromptSuffix string            // Extra instructions appended to the prompt
+}
+
+// CIOutput contains the description of the build and deployment pipeline
+type CIOutput struct {
+	Summary string
+}
+
+// DataModelInput contains the schema and migration files of a repository
+type DataModelInput struct {
+	Files        map[string]string // filename -> content
+	Language     string            // Natural language for the summary; empty for English
+	PromptSuffix string            // Extra instructions appended to the prompt
+}
+
+// DataModelOutput contains the description of the data model
+type DataModelOutput struct {
+	Summary string
+}
+
 // Component represents a code component identified by the LLM
 type Component struct {
-	Name        string
-	Type        string
-	Description string
-	Path        string
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Path        string `json:"path"`
 }
 
 // Config contains LLM client configuration
 type Config struct {
-	OpenAIKey string
-	APIBase   string
-	Model     string
+	OpenAIKey        string
+	APIBase          string
+	Model            string
+	MaxContinuations int // Follow-up requests allowed when a response is truncated
+	TokenBudget      int // Tokens allowed across all requests of the client; 0 is unlimited
+
+	// RequestsPerMinute paces the client's requests, including continuations,
+	// to stay under the provider's rate limit; 0 is unlimited
+	RequestsPerMinute int
+
+	// ExtraBody is merged into the JSON body of every chat completion request,
+	// replacing any field of the same name, e.g. {"seed": 42}
+	ExtraBody map[string]any
 }
 
+// Defaults applied when a profile leaves the endpoint or model empty
+const (
+	DefaultAPIBase = "https://api.openai.com/v1"
+	DefaultModel   = "gpt-3.5-turbo"
+)
+
+// DefaultConcurrency is the number of parallel requests made when analyzing
+// chunks, kept low to stay within typical API rate limits
+const DefaultConcurrency = 4
+
 // NewClient creates a new LLM client based on the configuration
 func NewClient(config Config) (Client, error) {
 	if config.OpenAIKey == "" {
@@ -68,11 +270,15 @@ func NewClient(config Config) (Client, error) {
 	}
 
 	if config.APIBase == "" {
-		config.APIBase = "https://api.openai.com/v1"
+		config.APIBase = DefaultAPIBase
 	}
 
 	if config.Model == "" {
-		config.Model = "gpt-3.5-turbo"
+		config.Model = DefaultModel
+	}
+
+	if config.MaxContinuations < 0 {
+		config.MaxContinuations = 0
 	}
 
 	return newOpenAIClient(config)
@@ -93,6 +299,14 @@ Please provide:
 
 Focus on the most important aspects and keep the response clear and concise.`
 
+// Response format appended to analysis prompts so the output can be parsed into AnalyzeOutput
+const structuredAnalysisFormat = `Respond with only a JSON object, no prose, with the fields:
+- "description": what the codebase does and why it exists (Markdown allowed)
+- "architecture": the main architectural pieces and how they relate (Markdown allowed)
+- "components": an array of objects with "name", "type", "path" and "description"
+- "setup": setup and build instructions if they can be inferred (Markdown allowed)
+- "flow_diagram": a Mermaid flowchart of the main components and their interactions, without code fences`
+
 // Template for the file explanation prompt
 const explainPrompt = `Explain the following file in detail:
 
@@ -108,3 +322,76 @@ Please provide:
 4. Any important patterns or considerations
 
 Keep the explanation clear and focused on the most important aspects.`
+
+// Template for the diff explanation prompt
+const explainDiffPrompt = `Explain how the following file changed between two versions:
+
+Filename: %s
+
+Version at %s:
+%s
+
+Version at %s:
+%s
+
+Please provide:
+1. A summary of what changed
+2. The likely reason for the change
+3. Why it matters: effects on behavior, callers, performance or security
+4. Anything a reviewer should check closely
+
+Focus on the changes rather than describing the unchanged code.`
+
+// Template for the changelog prompt
+const changelogPrompt = `Write release notes for the following commits:
+
+%s
+
+Please provide:
+1. A Markdown changelog grouped under the headings "Features", "Fixes" and "Chores"
+2. One concise, user-facing bullet per change, referencing the short commit hash
+3. Related commits merged into a single bullet where appropriate
+
+Omit empty groups and do not invent changes that are not in the commits.`
+
+// Template for the CI/CD summary prompt
+const ciPrompt = `Describe how this project is built, tested and deployed, based on its CI/CD
+configuration below. Cover what triggers each pipeline, its main jobs or stages,
+and where artifacts are published or deployed. Keep it to a short paragraph or
+a few bullets, and do not describe steps that are not in the configuration.
+
+%s`
+
+// Template for the data model summary prompt
+const dataModelPrompt = `Describe the data model of this project from its database schema and migration
+files below. Name the main entities or tables, their key fields, and how they
+relate to each other. Keep it to a short paragraph or a few bullets, and do not
+describe tables or fields that are not in the files. Later migrations override
+earlier ones and the schema files.
+
+%s`
+
+// Template for the component identification prompt
+const componentsPrompt = `Identify the main components of this codebase.
+
+Directory Structure:
+%s
+
+Languages:
+%s
+
+Key Files:
+%s
+%s
+%s, where each element has the fields:
+- "name": a short component name
+- "type": one of "API", "CLI", "Service", "Library", "Utility", "Config", "Test" or "Other"
+- "path": the directory or file that contains the component
+- "description": one sentence describing its responsibility`
+
+// Response formats of the components prompt: a single JSON array, or one
+// object per line so that each can be parsed as soon as it is streamed
+const (
+	componentsArrayFormat = "Respond with only a JSON array, no prose"
+	componentsLinesFormat = "Respond with only JSON objects, one per line, with no surrounding array, code fence or prose"
+)
@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestAnalyzeSerializesProgress runs a detailed analysis with concurrent
+// chunk requests; run it with -race to catch unsynchronized callbacks
+func TestAnalyzeSerializesProgress(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		requests.Add(1)
+		// Keep several chunk requests in flight at once
+		time.Sleep(5 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"choices":[{"message":{"content":"{\"description\":\"A test repository.\"}"},"finish_reason":"stop"}]}`)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := newOpenAIClient(Config{OpenAIKey: "sk-test", APIBase: server.URL, Model: "gpt-4o-mini"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		files[fmt.Sprintf("pkg/file%02d.go", i)] = "package pkg\n\n" + strings.Repeat("// filler\n", 20)
+	}
+
+	var (
+		inCallback  atomic.Int32
+		overlapped  atomic.Bool
+		lastCurrent = -1
+		lastTotal   int
+		responses   int
+		backwards   bool
+	)
+	progress := func(stage string, current, total int, response string) {
+		if inCallback.Add(1) > 1 {
+			overlapped.Store(true)
+		}
+		defer inCallback.Add(-1)
+		time.Sleep(time.Millisecond)
+
+		// Serialized calls may update shared state without locking
+		switch stage {
+		case "Analyzing chunks":
+			if current < lastCurrent {
+				backwards = true
+			}
+			lastCurrent, lastTotal = current, total
+		case "Analysis response":
+			responses++
+		}
+	}
+
+	_, err = client.Analyze(context.Background(), AnalyzeInput{
+		Files:       files,
+		ContextSize: 4000,
+		IsDetailed:  true,
+		ChunkSize:   400,
+		Concurrency: 4,
+	}, progress)
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	if overlapped.Load() {
+		t.Error("progress callback was called concurrently")
+	}
+	if backwards {
+		t.Error("chunk progress moved backwards")
+	}
+	if lastTotal < 2*4 {
+		t.Fatalf("analysis made %d chunks, too few to exercise concurrency", lastTotal)
+	}
+	if lastCurrent != lastTotal {
+		t.Errorf("final chunk progress = %d/%d, want all chunks completed", lastCurrent, lastTotal)
+	}
+	if responses != lastTotal {
+		t.Errorf("got %d chunk responses, want %d", responses, lastTotal)
+	}
+	// One request per chunk, then one for the summary
+	if got := int(requests.Load()); got != lastTotal+1 {
+		t.Errorf("sent %d requests, want %d", got, lastTotal+1)
+	}
+}
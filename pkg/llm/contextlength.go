@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// minSplitSize is the smallest chunk or file, in bytes, that is split or
+// truncated further after a context length error; anything smaller fails
+const minSplitSize = 500
+
+// contextLengthPhrases appear in the messages of endpoints that report context
+// length errors without OpenAI's context_length_exceeded code
+var contextLengthPhrases = []string{
+	"context length", "context_length", "context window", "maximum context", "too many tokens",
+}
+
+// isContextLength reports whether the error says the prompt was too long.
+// Rate limit errors may also mention tokens and never count.
+func (e *responseError) isContextLength() bool {
+	kind := strings.ToLower(e.Type + " " + e.Code + " " + e.Message)
+	if strings.Contains(kind, "rate_limit") {
+		return false
+	}
+	for _, phrase := range contextLengthPhrases {
+		if strings.Contains(kind, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeChunk analyzes one chunk of a detailed analysis. A chunk the model
+// cannot fit is split in two at a line boundary and each half analyzed in turn,
+// down to minSplitSize, with the halves' analyses joined.
+func (c *openAIClient) analyzeChunk(ctx context.Context, input AnalyzeInput, chunk string) (string, error) {
+	response, err := c.makeRequest(ctx, chunkPrompt(input, chunk), nil)
+	if !errors.Is(err, ErrContextLengthExceeded) || len(chunk) < minSplitSize {
+		return response, err
+	}
+
+	slog.Warn("chunk exceeds the model's context length; splitting it", "bytes", len(chunk))
+	first, second := splitInHalf(chunk)
+	firstResponse, err := c.analyzeChunk(ctx, input, first)
+	if err != nil {
+		return "", err
+	}
+	secondResponse, err := c.analyzeChunk(ctx, input, second)
+	if err != nil {
+		return "", err
+	}
+	return firstResponse + "\n\n" + secondResponse, nil
+}
+
+// explainTruncated explains a file, halving its content while the model
+// rejects it for length, and notes in the explanation how much was covered
+func (c *openAIClient) explainTruncated(ctx context.Context, input ExplainInput, stream StreamCallback) (string, error) {
+	content := input.Content
+	for {
+		prompt := withSuffix(fmt.Sprintf(explainPrompt, input.Filename, content)+languageInstruction(input.Language), input.PromptSuffix)
+		response, err := c.complete(ctx, []chatMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: prompt},
+		}, nil, stream)
+		if err == nil {
+			if len(content) < len(input.Content) {
+				note := fmt.Sprintf("\n\n> Note: the file was too long for the model's context, so only its first %d of %d lines were explained.",
+					strings.Count(content, "\n")+1, strings.Count(input.Content, "\n")+1)
+				if stream != nil {
+					stream(note)
+				}
+				response += note
+			}
+			return response, nil
+		}
+		if !errors.Is(err, ErrContextLengthExceeded) || len(content) < minSplitSize {
+			return "", err
+		}
+
+		content, _ = splitInHalf(content)
+		slog.Warn("file exceeds the model's context length; retrying with its first half", "bytes", len(content))
+	}
+}
+
+// splitInHalf splits text at the line break nearest its middle, or at the
+// middle character when it has no line breaks
+func splitInHalf(text string) (string, string) {
+	middle := len(text) / 2
+	before := strings.LastIndexByte(text[:middle], '\n')
+	after := strings.IndexByte(text[middle:], '\n')
+	switch {
+	case before > 0 && (after < 0 || middle-before <= after):
+		return text[:before], text[before+1:]
+	case after >= 0 && middle+after < len(text)-1:
+		return text[:middle+after], text[middle+after+1:]
+	}
+	for middle > 0 && !utf8.RuneStart(text[middle]) {
+		middle--
+	}
+	return text[:middle], text[middle:]
+}
@@ -0,0 +1,212 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+// Errors matched by errors.Is against the errors of requests the endpoint
+// rejected, so callers can tell why a request failed
+var (
+	// ErrUnauthorized means the endpoint rejected the API key
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrRateLimited means the endpoint throttled the request; retrying later
+	// may succeed. Exhausted quotas are not rate limits.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrModelNotFound means the endpoint does not serve the requested model
+	ErrModelNotFound = errors.New("model not found")
+
+	// ErrContextLengthExceeded means the prompt exceeds the model's context length
+	ErrContextLengthExceeded = errors.New("prompt exceeds the model's context length")
+
+	// ErrUnreachable means no connection to the endpoint could be made, e.g.
+	// because the API base names an unknown host or the server is down
+	ErrUnreachable = errors.New("endpoint unreachable")
+)
+
+// unreachableError replaces the transport error of a request that never
+// reached the endpoint with a short message naming the host
+type unreachableError struct {
+	Host  string
+	Cause string // e.g. "no such host" or "connection refused"
+	Err   error
+}
+
+func (e *unreachableError) Error() string {
+	return fmt.Sprintf("could not reach %s (%s): check the API base and your network", e.Host, e.Cause)
+}
+
+func (e *unreachableError) Unwrap() error { return e.Err }
+
+// Is matches ErrUnreachable
+func (e *unreachableError) Is(target error) bool {
+	return target == ErrUnreachable
+}
+
+// requestError wraps the error of a request that got no response. Failures to
+// resolve or connect to the host become an unreachableError; others, such as
+// a canceled context or a timeout waiting for the response, are kept as is.
+func requestError(req *http.Request, err error) error {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var cause string
+	switch {
+	case errors.As(err, &dnsErr):
+		cause = "no such host"
+		if !dnsErr.IsNotFound {
+			cause = "DNS lookup failed"
+		}
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		switch {
+		case errors.Is(err, syscall.ECONNREFUSED):
+			cause = "connection refused"
+		case opErr.Timeout():
+			cause = "connection timed out"
+		default:
+			cause = "connection failed"
+		}
+	default:
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	slog.Debug("endpoint unreachable", "url", req.URL.String(), "error", err)
+	return &unreachableError{Host: req.URL.Host, Cause: cause, Err: err}
+}
+
+// Is matches the sentinel errors from the status code and the error object of
+// the response body
+func (e *apiError) Is(target error) bool {
+	parsed := e.parsed()
+	if parsed == nil {
+		parsed = &responseError{}
+	}
+	return parsed.is(e.StatusCode, target)
+}
+
+// mentionsFormat reports whether the response body blames the request's
+// response_format, as endpoints without structured output support do
+func (e *apiError) mentionsFormat() bool {
+	body := strings.ToLower(e.Body)
+	return strings.Contains(body, "response_format") || strings.Contains(body, "json_schema")
+}
+
+// responseError is the error object OpenAI-compatible endpoints put in the
+// response body, either on a failed status or next to an empty choices list
+type responseError struct {
+	Message string
+	Type    string
+	Code    string
+}
+
+// parseResponseError extracts the top-level "error" field from a response body.
+// It accepts both the OpenAI object form and the plain string some proxies
+// return, and returns nil when the body has no error.
+func parseResponseError(raw json.RawMessage) *responseError {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+
+	var message string
+	if err := json.Unmarshal(raw, &message); err == nil {
+		return &responseError{Message: message}
+	}
+
+	var obj struct {
+		Message string          `json:"message"`
+		Type    string          `json:"type"`
+		Code    json.RawMessage `json:"code"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil
+	}
+	// The code is a string on OpenAI and a number on some other endpoints
+	code := strings.Trim(string(obj.Code), `"`)
+	if code == "null" {
+		code = ""
+	}
+	return &responseError{Message: obj.Message, Type: obj.Type, Code: code}
+}
+
+// is reports whether the error, returned with the given HTTP status, matches
+// a sentinel error. The status is 0 for errors returned with a 200 response.
+func (e *responseError) is(status int, target error) bool {
+	kind := strings.ToLower(e.Type + " " + e.Code)
+
+	switch target {
+	case ErrUnauthorized:
+		return status == http.StatusUnauthorized || strings.Contains(kind, "invalid_api_key")
+	case ErrRateLimited:
+		return !strings.Contains(kind, "insufficient_quota") &&
+			(status == http.StatusTooManyRequests || strings.Contains(kind, "rate_limit"))
+	case ErrModelNotFound:
+		// A 404 that does not mention the model usually means a wrong API base
+		return strings.Contains(kind, "model_not_found") ||
+			(status == http.StatusNotFound && strings.Contains(strings.ToLower(e.Message), "model"))
+	case ErrContextLengthExceeded:
+		return status != http.StatusTooManyRequests && e.isContextLength()
+	}
+	return false
+}
+
+// reason summarizes the error for users, e.g. "rate limited", using the HTTP
+// status when the error type is not recognized. The status is 0 for errors
+// returned with a 200 response.
+func (e *responseError) reason(status int) string {
+	kind := strings.ToLower(e.Type + " " + e.Code)
+	switch {
+	case strings.Contains(kind, "insufficient_quota"):
+		return "quota exceeded"
+	case strings.Contains(kind, "rate_limit") || status == http.StatusTooManyRequests || e.Code == "429":
+		return "rate limited"
+	case strings.Contains(kind, "content_filter") || strings.Contains(kind, "content_policy"):
+		return "content filtered"
+	case e.isContextLength():
+		return "prompt exceeds the model's context length"
+	case strings.Contains(kind, "invalid_api_key") || status == http.StatusUnauthorized:
+		return "invalid API key"
+	case strings.Contains(kind, "model_not_found"):
+		return "model not found"
+	case status >= http.StatusInternalServerError:
+		return "server error"
+	}
+	return "endpoint error"
+}
+
+// describe formats the error with its reason, e.g. "rate limited: Rate limit reached for gpt-4o"
+func (e *responseError) describe(status int) string {
+	if e.Message == "" {
+		return e.reason(status)
+	}
+	return fmt.Sprintf("%s: %s", e.reason(status), e.Message)
+}
+
+// choicesError is returned for a response without an error status that had
+// no choices but an error object, such as a rate limit reported by a proxy
+type choicesError struct {
+	parsed *responseError
+}
+
+func (e *choicesError) Error() string {
+	return fmt.Sprintf("no response from the model (%s)", e.parsed.describe(0))
+}
+
+// Is matches the sentinel errors from the error object, as apiError does
+func (e *choicesError) Is(target error) bool {
+	return e.parsed.is(0, target)
+}
+
+// emptyChoicesError explains why a response without an error status had no
+// usable choice, using the body's error field when present
+func emptyChoicesError(response *chatResponse) error {
+	if e := parseResponseError(response.Error); e != nil {
+		return &choicesError{parsed: e}
+	}
+	return fmt.Errorf("no response from the model: the endpoint returned no choices")
+}
@@ -0,0 +1,56 @@
+package llm
+
+// charsPerToken approximates how many characters of code and English text make
+// up one token for common tokenizers
+const charsPerToken = 4
+
+// estimatedChunkResponseTokens approximates the length of one chunk analysis,
+// which the summary request of a detailed analysis includes for every chunk
+const estimatedChunkResponseTokens = 300
+
+// RequestEstimate describes the requests an analysis would send
+type RequestEstimate struct {
+	Requests    int `json:"requests"`     // Chat completion requests, not counting continuations
+	Chunks      int `json:"chunks"`       // Chunks in a detailed analysis; 0 otherwise
+	InputTokens int `json:"input_tokens"` // Approximate prompt tokens across all requests
+}
+
+// EstimateTokens approximates the number of tokens in text
+func EstimateTokens(text string) int {
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// EstimateRequests builds the prompts an analysis of input would send, without
+// contacting the endpoint, and estimates their size
+func EstimateRequests(input AnalyzeInput) RequestEstimate {
+	if input.SummaryOnly {
+		input, _ = fitKeyFiles(input, summaryOnlyPrompt)
+		return RequestEstimate{Requests: 1, InputTokens: EstimateTokens(summaryOnlyPrompt(input))}
+	}
+	if !input.IsDetailed {
+		input, _ = fitKeyFiles(input, quickPrompt)
+		return RequestEstimate{Requests: 1, InputTokens: EstimateTokens(quickPrompt(input))}
+	}
+
+	chunks := buildChunks(input, nil)
+	tokens := 0
+	for _, chunk := range chunks {
+		tokens += EstimateTokens(chunkPrompt(input, chunk))
+	}
+	tokens += EstimateTokens(summaryPrompt(input, nil)) + len(chunks)*estimatedChunkResponseTokens
+	requests := len(chunks) + 1
+
+	if input.SummarizeFiles {
+		batches := fileSummaryBatches(input)
+		for _, batch := range batches {
+			tokens += EstimateTokens(fileSummariesBatchPrompt(input, batch))
+		}
+		requests += len(batches)
+	}
+
+	return RequestEstimate{
+		Requests:    requests,
+		Chunks:      len(chunks),
+		InputTokens: tokens,
+	}
+}
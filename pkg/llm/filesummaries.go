@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// Limits on the per-file summaries of a detailed analysis, which bound their
+// cost independently of the repository's size
+const (
+	maxSummarizedFiles  = 100  // Files summarized, largest first
+	maxFilesPerBatch    = 20   // Files summarized by one request
+	maxFileExcerptChars = 1200 // Characters of each file sent for its summary
+)
+
+// Template for the prompt summarizing a batch of files
+const fileSummariesPrompt = `Summarize each of the following files in one short sentence describing its
+responsibility.
+
+%s
+Respond with only a JSON object, no prose, mapping each file path exactly as
+given to its summary.%s`
+
+// summarizeFiles summarizes the significant files of a detailed analysis in
+// one sentence each, sending them in batches. A batch that fails is skipped
+// with a warning, and the token budget running out stops the remaining ones,
+// so the summaries never fail the analysis.
+func (c *openAIClient) summarizeFiles(ctx context.Context, input AnalyzeInput, progress ProgressCallback) map[string]string {
+	batches := fileSummaryBatches(input)
+	summaries := make(map[string]string)
+	for i, batch := range batches {
+		if progress != nil {
+			progress("Summarizing files", i, len(batches), "")
+		}
+
+		response, err := c.makeRequest(ctx, fileSummariesBatchPrompt(input, batch), nil)
+		if errors.Is(err, ErrTokenBudget) {
+			slog.Warn("token budget exhausted; skipping the remaining file summaries", "batches", len(batches)-i)
+			break
+		}
+		if err != nil {
+			slog.Warn("failed to summarize files", "batch", i+1, "error", err)
+			continue
+		}
+
+		parsed, err := parseFileSummaries(response)
+		if err != nil {
+			slog.Warn("failed to parse file summaries", "batch", i+1, "error", err)
+			continue
+		}
+		for _, name := range batch {
+			if summary := strings.TrimSpace(parsed[name]); summary != "" {
+				summaries[name] = summary
+			}
+		}
+	}
+	if progress != nil {
+		progress("Summarizing files", len(batches), len(batches), "")
+	}
+	return summaries
+}
+
+// fileSummaryBatches selects the files to summarize, the largest source files
+// up to maxSummarizedFiles, and groups them into batches that fit in about
+// half the context
+func fileSummaryBatches(input AnalyzeInput) [][]string {
+	var names []string
+	for name := range input.Files {
+		if !isReadme(name) && markupFormat(name) == "" {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(input.Files[names[i]]) != len(input.Files[names[j]]) {
+			return len(input.Files[names[i]]) > len(input.Files[names[j]])
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > maxSummarizedFiles {
+		names = names[:maxSummarizedFiles]
+	}
+	sort.Strings(names)
+
+	contextSize := input.ContextSize
+	if contextSize <= 0 {
+		contextSize = 4000
+	}
+	budget := contextSize * 2
+
+	var batches [][]string
+	var batch []string
+	used := 0
+	for _, name := range names {
+		size := min(len(input.Files[name]), maxFileExcerptChars)
+		if len(batch) > 0 && (used+size > budget || len(batch) == maxFilesPerBatch) {
+			batches = append(batches, batch)
+			batch, used = nil, 0
+		}
+		batch = append(batch, name)
+		used += size
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// fileSummariesBatchPrompt builds the prompt summarizing one batch of files,
+// sending only the beginning of long files
+func fileSummariesBatchPrompt(input AnalyzeInput, batch []string) string {
+	var b strings.Builder
+	for _, name := range batch {
+		content := input.Files[name]
+		if len(content) > maxFileExcerptChars {
+			content = content[:maxFileExcerptChars] + "\n... (truncated)"
+		}
+		fmt.Fprintf(&b, "File: %s\n\n%s\n\n", name, content)
+	}
+	return withSuffix(fmt.Sprintf(fileSummariesPrompt, b.String(), languageInstruction(input.Language)), input.PromptSuffix)
+}
+
+// parseFileSummaries decodes the JSON object of a file summaries response
+func parseFileSummaries(response string) (map[string]string, error) {
+	object, ok := extractJSON(response, '{', '}')
+	if !ok {
+		return nil, fmt.Errorf("no JSON object found")
+	}
+	var summaries map[string]string
+	if err := json.Unmarshal([]byte(object), &summaries); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
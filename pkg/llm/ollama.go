@@ -18,6 +18,34 @@ func (c *ollamaClient) Analyze(ctx context.Context, input AnalyzeInput, progress
 	return nil, fmt.Errorf("Ollama integration not implemented yet")
 }
 
-func (c *ollamaClient) ExplainFile(ctx context.Context, input ExplainInput) (*ExplainOutput, error) {
+func (c *ollamaClient) ExplainFile(ctx context.Context, input ExplainInput, stream StreamCallback) (*ExplainOutput, error) {
+	return nil, fmt.Errorf("Ollama integration not implemented yet")
+}
+
+func (c *ollamaClient) ExplainDiff(ctx context.Context, input ExplainDiffInput, stream StreamCallback) (*ExplainOutput, error) {
+	return nil, fmt.Errorf("Ollama integration not implemented yet")
+}
+
+func (c *ollamaClient) Changelog(ctx context.Context, input ChangelogInput) (*ChangelogOutput, error) {
+	return nil, fmt.Errorf("Ollama integration not implemented yet")
+}
+
+func (c *ollamaClient) Chat(ctx context.Context, input ChatInput) (*ChatOutput, error) {
+	return nil, fmt.Errorf("Ollama integration not implemented yet")
+}
+
+func (c *ollamaClient) SummarizeCI(ctx context.Context, input CIInput) (*CIOutput, error) {
+	return nil, fmt.Errorf("Ollama integration not implemented yet")
+}
+
+func (c *ollamaClient) SummarizeDataModel(ctx context.Context, input DataModelInput) (*DataModelOutput, error) {
+	return nil, fmt.Errorf("Ollama integration not implemented yet")
+}
+
+func (c *ollamaClient) IdentifyComponents(ctx context.Context, input AnalyzeInput, found ComponentCallback) ([]Component, error) {
+	return nil, fmt.Errorf("Ollama integration not implemented yet")
+}
+
+func (c *ollamaClient) ListModels(ctx context.Context) ([]string, error) {
 	return nil, fmt.Errorf("Ollama integration not implemented yet")
 }
@@ -4,103 +4,568 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/time/rate"
 )
 
 type openAIClient struct {
-	apiKey  string
-	apiBase string
-	model   string
-	client  *http.Client
+	apiKey           string
+	apiBase          string
+	model            string
+	maxContinuations int
+	client           *http.Client
+
+	// schemaUnsupported is set once the endpoint rejects response_format, so
+	// later requests go straight to prompt-based JSON
+	schemaUnsupported atomic.Bool
+
+	tokenBudget int          // Tokens the run may use across all requests; 0 is unlimited
+	tokensUsed  atomic.Int64 // Tokens used so far, as reported or estimated
+
+	extraBody map[string]any // Fields merged into every request body, replacing repo-sage's own
+
+	limiter *rate.Limiter // Paces requests to Config.RequestsPerMinute; nil is unlimited
 }
 
+// systemPrompt is the system message sent with every request
+const systemPrompt = "You are a helpful AI assistant that analyzes and explains code."
+
 type chatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
 }
 
 type chatRequest struct {
-	Model    string        `json:"model"`
-	Messages []chatMessage `json:"messages"`
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+	Stream         bool            `json:"stream,omitempty"`
+}
+
+// responseFormat asks compliant endpoints to constrain output to a JSON schema
+type responseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *jsonSchemaFormat `json:"json_schema,omitempty"`
+}
+
+type jsonSchemaFormat struct {
+	Name   string          `json:"name"`
+	Strict bool            `json:"strict"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// analysisFormat requests output matching structuredAnalysis
+var analysisFormat = &responseFormat{
+	Type: "json_schema",
+	JSONSchema: &jsonSchemaFormat{
+		Name:   "analysis",
+		Strict: true,
+		Schema: json.RawMessage(analysisSchema),
+	},
+}
+
+// apiError is returned when the endpoint responds with a non-200 status
+type apiError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *apiError) Error() string {
+	if parsed := e.parsed(); parsed != nil {
+		return fmt.Sprintf("API request failed with status %d (%s)", e.StatusCode, parsed.describe(e.StatusCode))
+	}
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// parsed returns the error object of the response body, or nil if it has none
+func (e *apiError) parsed() *responseError {
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal([]byte(e.Body), &body) != nil {
+		return nil
+	}
+	return parseResponseError(body.Error)
 }
 
 type chatResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"choices"`
+	Choices []chatChoice    `json:"choices"`
+	Error   json.RawMessage `json:"error"` // Set by some endpoints alongside empty choices
+	Usage   *chatUsage      `json:"usage"`
 }
 
-// ProgressCallback is called to report progress during analysis
-type ProgressCallback func(stage string, current, total int, response string)
+type chatChoice struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	FinishReason string `json:"finish_reason"`
+}
+
+// continuePrompt asks the model to resume a response that hit the output token limit
+const continuePrompt = "Your previous response was cut off. Continue exactly where you left off, without repeating anything you already wrote."
 
 func newOpenAIClient(config Config) (Client, error) {
+	var limiter *rate.Limiter
+	if config.RequestsPerMinute > 0 {
+		// A burst of one spreads requests evenly instead of sending a minute's worth at once
+		limiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(config.RequestsPerMinute)), 1)
+	}
 	return &openAIClient{
-		apiKey:  config.OpenAIKey,
-		apiBase: config.APIBase,
-		model:   config.Model,
-		client:  &http.Client{},
+		apiKey:           config.OpenAIKey,
+		apiBase:          normalizeAPIBase(config.APIBase),
+		model:            config.Model,
+		maxContinuations: config.MaxContinuations,
+		client:           &http.Client{},
+		tokenBudget:      config.TokenBudget,
+		extraBody:        config.ExtraBody,
+		limiter:          limiter,
 	}, nil
 }
 
-func (c *openAIClient) makeRequest(ctx context.Context, prompt string) (string, error) {
+// normalizeAPIBase trims trailing slashes from an API base URL, which would
+// otherwise produce "//chat/completions", and collapses a duplicated "/v1"
+// such as ".../v1/v1", which endpoints answer with 404
+func normalizeAPIBase(base string) string {
+	normalized := strings.TrimRight(base, "/")
+	for strings.HasSuffix(normalized, "/v1/v1") {
+		normalized = strings.TrimSuffix(normalized, "/v1")
+	}
+	if strings.Contains(normalized, "/v1/v1/") {
+		slog.Warn("API base contains a duplicated /v1, which usually causes 404 responses", "api_base", base)
+	} else if normalized != strings.TrimRight(base, "/") {
+		slog.Warn("API base ends with a duplicated /v1; using "+normalized, "api_base", base)
+	}
+	return normalized
+}
+
+// makeRequest sends a prompt and returns the full response, asking the model to
+// continue when the output is truncated by its token limit. A non-nil format
+// requests schema-constrained JSON; endpoints that reject it get the same
+// prompt without one, relying on the prompt's own JSON instructions.
+func (c *openAIClient) makeRequest(ctx context.Context, prompt string, format *responseFormat) (string, error) {
+	return c.complete(ctx, []chatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: prompt},
+	}, format, nil)
+}
+
+// complete sends a conversation and returns the full response to its last
+// message, with the same continuation and format handling as makeRequest. A
+// non-nil stream receives the response, continuations included, as it arrives.
+func (c *openAIClient) complete(ctx context.Context, messages []chatMessage, format *responseFormat, stream StreamCallback) (string, error) {
+	if c.schemaUnsupported.Load() {
+		format = nil
+	}
+
+	var result strings.Builder
+	for continuation := 0; ; continuation++ {
+		content, finishReason, err := c.sendChat(ctx, messages, format, stream)
+		var apiErr *apiError
+		if format != nil && errors.As(err, &apiErr) && !errors.Is(err, ErrContextLengthExceeded) &&
+			(apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusUnprocessableEntity) {
+			// Only an error naming the format disables it for the rest of the
+			// run; other bad requests are retried once without it
+			if apiErr.mentionsFormat() {
+				slog.Debug("endpoint rejected response_format, falling back to prompt-based JSON", "status", apiErr.StatusCode)
+				c.schemaUnsupported.Store(true)
+			} else {
+				slog.Debug("request with response_format failed, retrying without it", "status", apiErr.StatusCode)
+			}
+			format = nil
+			content, finishReason, err = c.sendChat(ctx, messages, nil, stream)
+		}
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(content)
+
+		if finishReason != "length" {
+			break
+		}
+		if continuation >= c.maxContinuations {
+			slog.Warn("response truncated by the model's output limit", "continuations", continuation)
+			break
+		}
+
+		// A continuation resumes partial JSON, which a schema would reject
+		format = nil
+		slog.Debug("response truncated, requesting continuation", "continuation", continuation+1)
+		messages = append(messages,
+			chatMessage{Role: "assistant", Content: content},
+			chatMessage{Role: "user", Content: continuePrompt},
+		)
+	}
+
+	return result.String(), nil
+}
+
+// sendChat performs a single chat completion request and returns the content
+// and finish reason. With a non-nil stream, the response is requested as
+// server-sent events and passed to stream as it arrives.
+func (c *openAIClient) sendChat(ctx context.Context, messages []chatMessage, format *responseFormat, stream StreamCallback) (string, string, error) {
+	if err := c.checkBudget(messages); err != nil {
+		return "", "", err
+	}
+
 	reqBody := chatRequest{
-		Model: c.model,
-		Messages: []chatMessage{
-			{Role: "system", Content: "You are a helpful AI assistant that analyzes and explains code."},
-			{Role: "user", Content: prompt},
-		},
+		Model:          c.model,
+		Messages:       messages,
+		ResponseFormat: format,
+		Stream:         stream != nil,
 	}
 
 	reqData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	if reqData, err = mergeExtraBody(reqData, c.extraBody); err != nil {
+		return "", "", err
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", c.apiBase+"/chat/completions", bytes.NewReader(reqData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return "", "", fmt.Errorf("failed to wait for the request rate limit: %w", err)
+		}
+	}
+
+	slog.Debug("sending chat completion request", "url", req.URL.String(), "model", c.model, "messages", len(messages))
+	start := time.Now()
+
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
+		return "", "", requestError(req, err)
 	}
 	defer resp.Body.Close()
 
+	slog.Debug("received chat completion response", "status", resp.StatusCode, "duration", time.Since(start))
+
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		slog.Debug("chat completion request failed", "status", resp.StatusCode, "body", string(body))
+		return "", "", &apiError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var response chatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	if stream != nil {
+		err = readStream(resp, &response, stream)
+	} else {
+		err = json.NewDecoder(resp.Body).Decode(&response)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	content := ""
+	if len(response.Choices) > 0 {
+		content = response.Choices[0].Message.Content
+	}
+	c.recordUsage(messages, content, response.Usage)
+
 	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no response choices returned")
+		return "", "", emptyChoicesError(&response)
+	}
+
+	choice := response.Choices[0]
+	if choice.FinishReason == "content_filter" {
+		if choice.Message.Content == "" {
+			return "", "", fmt.Errorf("no response from the model (content filtered): the endpoint's content filter blocked it")
+		}
+		slog.Warn("response cut short by the endpoint's content filter")
+	}
+
+	return choice.Message.Content, choice.FinishReason, nil
+}
+
+// mergeExtraBody adds user-configured fields to a marshaled request body. A
+// field the request already has is replaced, so users can override anything
+// repo-sage sends, including the model.
+func mergeExtraBody(body []byte, extra map[string]any) ([]byte, error) {
+	if len(extra) == 0 {
+		return body, nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("failed to merge extra body fields: %w", err)
+	}
+	for key, value := range extra {
+		fields[key] = value
+	}
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal extra body fields: %w", err)
+	}
+	return merged, nil
+}
+
+type modelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+func (c *openAIClient) ListModels(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.apiBase+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, requestError(req, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &apiError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var response modelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return response.Choices[0].Message.Content, nil
+	models := make([]string, 0, len(response.Data))
+	for _, m := range response.Data {
+		models = append(models, m.ID)
+	}
+	sort.Strings(models)
+	return models, nil
 }
 
 func (c *openAIClient) Analyze(ctx context.Context, input AnalyzeInput, progress ProgressCallback) (*AnalyzeOutput, error) {
-	// For quick summary, use a single prompt with directory structure and important files
+	progress = serializeProgress(progress)
+
+	if input.SummaryOnly {
+		return c.summarize(ctx, input, progress)
+	}
+
+	// For standard analysis, use a single prompt with directory structure and important files
 	if !input.IsDetailed {
 		if progress != nil {
 			progress("Preparing quick summary", 0, 1, "")
 		}
 
-		prompt := fmt.Sprintf(`Analyze this codebase and provide a quick overview:
+		input, trimmed := fitKeyFiles(input, quickPrompt)
+		response, err := c.makeRequest(ctx, quickPrompt(input), analysisFormat)
+		if err != nil {
+			return nil, err
+		}
+
+		if progress != nil {
+			progress("Quick summary", 1, 1, response)
+		}
+
+		output := parseAnalysis(response)
+		output.Trimmed = trimmed
+		return output, nil
+	}
+
+	// For detailed analysis, process all files in chunks
+	chunks := buildChunks(input, progress)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no content to analyze")
+	}
+
+	// Reuse checkpointed chunks first so the remaining-time estimate only
+	// reflects chunks that need a request
+	descriptions := make([]string, len(chunks))
+	var pending []int
+	for i, chunk := range chunks {
+		if input.Checkpoint != nil {
+			if response, ok := input.Checkpoint.Load(checkpointKey(input, chunk)); ok {
+				slog.Debug("reusing checkpointed chunk analysis", "chunk", i+1)
+				descriptions[i] = response
+				continue
+			}
+		}
+		pending = append(pending, i)
+	}
+	completed := len(chunks) - len(pending)
+	if progress != nil {
+		progress("Analyzing chunks", completed, len(chunks), "")
+	}
+
+	// Analyze the remaining chunks concurrently, keeping results in chunk order
+	workers := input.Concurrency
+	if workers <= 0 {
+		workers = DefaultConcurrency
+	}
+	chunkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu        sync.Mutex
+		firstErr  error
+		requested int
+		wg        sync.WaitGroup
+	)
+	start := time.Now()
+	jobs := make(chan int)
+	for w := 0; w < workers && w < len(pending); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				response, err := c.analyzeChunk(chunkCtx, input, chunks[i])
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("failed to analyze chunk %d: %w", i+1, err)
+						cancel()
+					}
+					mu.Unlock()
+					continue
+				}
+
+				if input.Checkpoint != nil {
+					if err := input.Checkpoint.Save(checkpointKey(input, chunks[i]), response); err != nil {
+						slog.Warn("failed to save checkpoint", "chunk", i+1, "error", err)
+					}
+				}
+
+				// Report under the lock so progress never moves backwards
+				mu.Lock()
+				descriptions[i] = response
+				completed++
+				requested++
+				if progress != nil {
+					progress("Analyzing chunks", completed, len(chunks), "")
+					progress("Analysis response", i+1, len(chunks), response)
+					remaining := time.Since(start) / time.Duration(requested) * time.Duration(len(pending)-requested)
+					progress("Estimated time remaining", completed, len(chunks), remaining.Round(time.Second).String())
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+send:
+	for _, i := range pending {
+		select {
+		case jobs <- i:
+		case <-chunkCtx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		if errors.Is(firstErr, ErrTokenBudget) {
+			return partialAnalysis(input, chunks, descriptions, firstErr)
+		}
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Combine the results into a structured overview
+	if progress != nil {
+		progress("Generating summary", 0, 1, "")
+	}
+
+	finalResponse, err := c.makeRequest(ctx, summaryPrompt(input, descriptions), analysisFormat)
+	if errors.Is(err, ErrTokenBudget) {
+		return partialAnalysis(input, chunks, descriptions, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate summary: %w", err)
+	}
+
+	if progress != nil {
+		progress("Final summary", 1, 1, finalResponse)
+	}
+
+	output := parseAnalysis(finalResponse)
+	output.Chunks = make([]ChunkAnalysis, len(chunks))
+	for i, chunk := range chunks {
+		output.Chunks[i] = ChunkAnalysis{Prompt: chunkPrompt(input, chunk), Response: descriptions[i]}
+	}
+	if input.SummarizeFiles {
+		output.FileSummaries = c.summarizeFiles(ctx, input, progress)
+	}
+	return output, nil
+}
+
+// partialAnalysis returns the chunk analyses completed before the token budget
+// ran out, without a summary, so a stopped run still produces documentation
+func partialAnalysis(input AnalyzeInput, chunks, descriptions []string, cause error) (*AnalyzeOutput, error) {
+	output := &AnalyzeOutput{}
+	var done []string
+	for i, description := range descriptions {
+		if description == "" {
+			continue
+		}
+		done = append(done, description)
+		output.Chunks = append(output.Chunks, ChunkAnalysis{Prompt: chunkPrompt(input, chunks[i]), Response: description})
+	}
+	if len(done) == 0 {
+		return nil, cause
+	}
+
+	output.Architecture = strings.Join(done, "\n\n")
+	if len(done) == len(chunks) {
+		output.Incomplete = fmt.Sprintf("%v before the summary; the %d chunk analyses are shown without one.", cause, len(done))
+	} else {
+		output.Incomplete = fmt.Sprintf("%v; only %d of %d chunks were analyzed, and they are shown without a summary.", cause, len(done), len(chunks))
+	}
+	return output, nil
+}
+
+// summarize asks for a one-paragraph description in a single request. It skips
+// continuations and the structured response format, so it never sends more.
+func (c *openAIClient) summarize(ctx context.Context, input AnalyzeInput, progress ProgressCallback) (*AnalyzeOutput, error) {
+	if progress != nil {
+		progress("Preparing quick summary", 0, 1, "")
+	}
+
+	input, trimmed := fitKeyFiles(input, summaryOnlyPrompt)
+	content, finishReason, err := c.sendChat(ctx, []chatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: summaryOnlyPrompt(input)},
+	}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if finishReason == "length" {
+		slog.Warn("summary truncated by the model's output limit")
+	}
+
+	if progress != nil {
+		progress("Quick summary", 1, 1, content)
+	}
+
+	return &AnalyzeOutput{Description: strings.TrimSpace(content), Trimmed: trimmed}, nil
+}
+
+// summaryOnlyPrompt builds the prompt of a quick analysis, which asks for
+// nothing but a paragraph describing the repository
+func summaryOnlyPrompt(input AnalyzeInput) string {
+	return withSuffix(fmt.Sprintf(`Describe what this codebase is and what it does in one short paragraph of
+plain prose, without headings, lists or code.
 
 Directory Structure:
 %s
@@ -108,54 +573,80 @@ Directory Structure:
 Languages:
 %s
 
-Please provide:
-1. A brief description of what this codebase likely does
-2. Main components and their purpose (based on directory structure)
-3. Technologies used (based on file types and languages)
-4. Setup/build system (based on manifest files)
+Key Files:
+%s%s`, input.DirStructure, formatLanguages(input.Languages), formatKeyFiles(input.Files), languageInstruction(input.Language)), input.PromptSuffix)
+}
 
-Focus on high-level understanding and keep it concise.`, input.DirStructure, formatLanguages(input.Languages))
+// quickPrompt builds the single prompt of a standard analysis
+func quickPrompt(input AnalyzeInput) string {
+	return withSuffix(fmt.Sprintf(`Analyze this codebase and provide a quick overview:
 
-		response, err := c.makeRequest(ctx, prompt)
-		if err != nil {
-			return nil, err
-		}
+Directory Structure:
+%s
 
-		if progress != nil {
-			progress("Quick summary", 1, 1, response)
-		}
+Languages:
+%s
 
-		return &AnalyzeOutput{
-			Description:  response,
-			Architecture: "",
-			Components:   nil,
-			Setup:        "",
-			FlowDiagram:  "",
-		}, nil
-	}
+Key Files:
+%s
+%s
+Base the description and components on the directory structure, the technologies
+on file types and languages, and the setup on the manifest files. Focus on
+high-level understanding and keep it concise.
 
-	// For detailed analysis, process all files in chunks
-	// Sort files by size to process most important files first
+%s%s`, input.DirStructure, formatLanguages(input.Languages), formatKeyFiles(input.Files), formatKnownComponents(input.KnownComponents)+formatSubprojects(input.Subprojects), structuredAnalysisFormat, languageInstruction(input.Language)), input.PromptSuffix)
+}
+
+// chunkPrompt builds the prompt for one chunk of a detailed analysis
+func chunkPrompt(input AnalyzeInput, chunk string) string {
+	return withSuffix(fmt.Sprintf("Analyze this part of the codebase. Focus on key components, patterns, and functionality. Be concise:\n\n%s", chunk), input.PromptSuffix)
+}
+
+// checkpointKey identifies a chunk's analysis in a Checkpoint by its prompt,
+// which holds the prompt suffix, and the language, so that resuming with other
+// instructions does not reuse responses written for the old ones
+func checkpointKey(input AnalyzeInput, chunk string) string {
+	return input.Language + "\x00" + chunkPrompt(input, chunk)
+}
+
+// summaryPrompt builds the prompt combining the chunk analyses of a detailed analysis
+func summaryPrompt(input AnalyzeInput, descriptions []string) string {
+	return withSuffix(fmt.Sprintf("Combine these analysis parts into a concise overview focusing on key components and architecture:\n\n%s\n%s\n%s%s", strings.Join(descriptions, "\n\n---\n\n"), formatKnownComponents(input.KnownComponents)+formatSubprojects(input.Subprojects), structuredAnalysisFormat, languageInstruction(input.Language)), input.PromptSuffix)
+}
+
+// buildChunks packs the input files into overlapping chunks for detailed analysis
+func buildChunks(input AnalyzeInput, progress ProgressCallback) []string {
+	// Order files so the most important are read first, and fit in the
+	// token budget when it runs out: see fileRank, then the most imported
+	// and the shortest files
 	type fileInfo struct {
-		name    string
-		content string
+		name      string
+		content   string
+		rank      int
+		importers int
 	}
 	files := make([]fileInfo, 0, len(input.Files))
 	for name, content := range input.Files {
-		files = append(files, fileInfo{name, content})
+		files = append(files, fileInfo{name, content, fileRank(name, content), input.Importers[name]})
 	}
 	sort.Slice(files, func(i, j int) bool {
-		// Prioritize main files and shorter files
-		iMain := strings.Contains(files[i].name, "main.") || strings.Contains(files[i].name, "index.")
-		jMain := strings.Contains(files[j].name, "main.") || strings.Contains(files[j].name, "index.")
-		if iMain != jMain {
-			return iMain
+		if files[i].rank != files[j].rank {
+			return files[i].rank > files[j].rank
+		}
+		if files[i].importers != files[j].importers {
+			return files[i].importers > files[j].importers
+		}
+		if len(files[i].content) != len(files[j].content) {
+			return len(files[i].content) < len(files[j].content)
 		}
-		return len(files[i].content) < len(files[j].content)
+		return files[i].name < files[j].name
 	})
 
 	// Process files in chunks
-	const maxChunkSize = 1500 // characters per chunk
+	maxChunkSize := input.ChunkSize
+	if maxChunkSize <= 0 {
+		maxChunkSize = DefaultChunkSize(input.ContextSize)
+	}
 	var chunks []string
 	currentChunk := strings.Builder{}
 
@@ -172,7 +663,7 @@ Focus on high-level understanding and keep it concise.`, input.DirStructure, for
 			}
 			// If the file is too large, split it into smaller chunks
 			if len(fileContent) > maxChunkSize {
-				parts := splitLongContent(fileContent, maxChunkSize)
+				parts := splitLongContent(file.name, file.content, maxChunkSize)
 				chunks = append(chunks, parts...)
 				continue
 			}
@@ -182,86 +673,93 @@ Focus on high-level understanding and keep it concise.`, input.DirStructure, for
 	if currentChunk.Len() > 0 {
 		chunks = append(chunks, currentChunk.String())
 	}
+	return overlapChunks(chunks, input.ChunkOverlap)
+}
 
-	// Analyze each chunk
-	var descriptions []string
-	for i, chunk := range chunks {
-		if progress != nil {
-			progress("Analyzing chunks", i+1, len(chunks), "")
-		}
-
-		prompt := fmt.Sprintf("Analyze this part of the codebase. Focus on key components, patterns, and functionality. Be concise:\n\n%s", chunk)
-		response, err := c.makeRequest(ctx, prompt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to analyze chunk %d: %w", i+1, err)
-		}
-
-		if progress != nil {
-			progress("Analysis response", i+1, len(chunks), response)
-		}
+func formatLanguages(langs map[string]float64) string {
+	var result []string
+	for lang, pct := range langs {
+		result = append(result, fmt.Sprintf("%s (%.1f%%)", lang, pct))
+	}
+	sort.Strings(result)
+	return strings.Join(result, ", ")
+}
 
-		descriptions = append(descriptions, response)
+// DefaultChunkSize returns the detailed-analysis chunk size for a context size:
+// 3/8 of it, which is 1500 characters for the default context of 4000, leaving
+// room for the prompt and the response
+func DefaultChunkSize(contextSize int) int {
+	if contextSize <= 0 {
+		contextSize = 4000
 	}
+	return contextSize * 3 / 8
+}
 
-	// Combine the results
-	if len(descriptions) > 1 {
-		if progress != nil {
-			progress("Generating summary", 0, 1, "")
-		}
+// overlapChunks prefixes every chunk after the first with the tail of the one
+// before it, trimmed to whole lines, so code spanning a boundary keeps its context
+func overlapChunks(chunks []string, overlap int) []string {
+	if overlap <= 0 || len(chunks) < 2 {
+		return chunks
+	}
 
-		summaryPrompt := fmt.Sprintf("Combine these analysis parts into a concise overview focusing on key components and architecture:\n\n%s", strings.Join(descriptions, "\n\n---\n\n"))
-		finalResponse, err := c.makeRequest(ctx, summaryPrompt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate summary: %w", err)
+	overlapped := make([]string, len(chunks))
+	overlapped[0] = chunks[0]
+	for i := 1; i < len(chunks); i++ {
+		tail := chunks[i-1]
+		if len(tail) > overlap {
+			tail = tail[len(tail)-overlap:]
+			if nl := strings.IndexByte(tail, '\n'); nl != -1 {
+				tail = tail[nl+1:]
+			}
 		}
-
-		if progress != nil {
-			progress("Final summary", 1, 1, finalResponse)
+		tail = strings.TrimSpace(tail)
+		if tail == "" {
+			overlapped[i] = chunks[i]
+			continue
 		}
-
-		descriptions = []string{finalResponse}
+		overlapped[i] = fmt.Sprintf("End of the previous part, for context:\n%s\n---\n%s", tail, chunks[i])
 	}
-
-	return &AnalyzeOutput{
-		Description:  descriptions[0],
-		Architecture: "",
-		Components:   nil,
-		Setup:        "",
-		FlowDiagram:  "",
-	}, nil
+	return overlapped
 }
 
-func formatLanguages(langs map[string]float64) string {
-	var result []string
-	for lang, pct := range langs {
-		result = append(result, fmt.Sprintf("%s (%.1f%%)", lang, pct))
+// splitLongContent splits a file that doesn't fit in one chunk at line boundaries.
+// Every chunk starts with a File header, marked as continued after the first,
+// so the model always knows which file the code belongs to. Lines longer than
+// a chunk are split without breaking UTF-8 characters.
+func splitLongContent(filename, content string, maxSize int) []string {
+	if content == "" {
+		return nil
 	}
-	sort.Strings(result)
-	return strings.Join(result, ", ")
-}
 
-func splitLongContent(content string, maxSize int) []string {
-	var chunks []string
+	header := fmt.Sprintf("File: %s\n\n", filename)
+	continued := fmt.Sprintf("File: %s (continued)\n\n", filename)
+	budget := maxSize - len(continued)
+	if budget < utf8.UTFMax {
+		// Filenames longer than a chunk still make progress
+		budget = utf8.UTFMax
+	}
+
+	var parts []string
 	lines := strings.Split(content, "\n")
 	currentChunk := strings.Builder{}
 
 	for _, line := range lines {
-		if currentChunk.Len()+len(line)+1 > maxSize {
-			if currentChunk.Len() > 0 {
-				chunks = append(chunks, currentChunk.String())
-				currentChunk.Reset()
+		if currentChunk.Len() > 0 && currentChunk.Len()+len(line)+1 > budget {
+			parts = append(parts, currentChunk.String())
+			currentChunk.Reset()
+		}
+		// If a single line is too long, split it
+		for len(line) > budget {
+			end := budget
+			for end > 0 && !utf8.RuneStart(line[end]) {
+				end--
 			}
-			// If a single line is too long, split it
-			if len(line) > maxSize {
-				for i := 0; i < len(line); i += maxSize {
-					end := i + maxSize
-					if end > len(line) {
-						end = len(line)
-					}
-					chunks = append(chunks, line[i:end])
-				}
-				continue
+			if end == 0 {
+				// Invalid UTF-8; split at the byte limit
+				end = budget
 			}
+			parts = append(parts, line[:end])
+			line = line[end:]
 		}
 		if currentChunk.Len() > 0 {
 			currentChunk.WriteString("\n")
@@ -269,14 +767,22 @@ func splitLongContent(content string, maxSize int) []string {
 		currentChunk.WriteString(line)
 	}
 	if currentChunk.Len() > 0 {
-		chunks = append(chunks, currentChunk.String())
+		parts = append(parts, currentChunk.String())
+	}
+
+	chunks := make([]string, len(parts))
+	for i, part := range parts {
+		if i == 0 {
+			chunks[i] = header + part
+		} else {
+			chunks[i] = continued + part
+		}
 	}
 	return chunks
 }
 
-func (c *openAIClient) ExplainFile(ctx context.Context, input ExplainInput) (*ExplainOutput, error) {
-	prompt := fmt.Sprintf(explainPrompt, input.Filename, input.Content)
-	response, err := c.makeRequest(ctx, prompt)
+func (c *openAIClient) ExplainFile(ctx context.Context, input ExplainInput, stream StreamCallback) (*ExplainOutput, error) {
+	response, err := c.explainTruncated(ctx, input, stream)
 	if err != nil {
 		return nil, err
 	}
@@ -287,3 +793,253 @@ func (c *openAIClient) ExplainFile(ctx context.Context, input ExplainInput) (*Ex
 		Components:  nil,
 	}, nil
 }
+
+func (c *openAIClient) ExplainDiff(ctx context.Context, input ExplainDiffInput, stream StreamCallback) (*ExplainOutput, error) {
+	old := input.Old
+	if old == "" {
+		old = "(the file does not exist in this version)"
+	}
+	prompt := withSuffix(fmt.Sprintf(explainDiffPrompt, input.Filename, input.Base, old, input.Head, input.New)+languageInstruction(input.Language), input.PromptSuffix)
+	response, err := c.complete(ctx, []chatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: prompt},
+	}, nil, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExplainOutput{Explanation: response}, nil
+}
+
+// Size limits for key files included in single-prompt requests. READMEs get a
+// larger budget because they usually describe the project best.
+const (
+	maxKeyFileSize    = 2000
+	maxReadmeFileSize = 6000
+)
+
+// formatKeyFiles renders the README first and the remaining files in name order,
+// labelling documentation markup so the model reads it as prose, not code
+func formatKeyFiles(files map[string]string) string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		iReadme, jReadme := isReadme(names[i]), isReadme(names[j])
+		if iReadme != jReadme {
+			return iReadme
+		}
+		return names[i] < names[j]
+	})
+
+	var b strings.Builder
+	for _, name := range names {
+		content := truncateMiddle(files[name], keyFileLimit(name))
+
+		if markup := markupFormat(name); markup != "" {
+			fmt.Fprintf(&b, "File: %s (%s documentation)\n\n%s\n\n", name, markup, content)
+		} else {
+			fmt.Fprintf(&b, "File: %s\n\n%s\n\n", name, content)
+		}
+	}
+	return b.String()
+}
+
+// maxKnownComponents bounds how many statically detected components are listed in a prompt
+const maxKnownComponents = 100
+
+// formatKnownComponents lists statically detected components for the model to
+// describe, or returns an empty string when there are none
+func formatKnownComponents(components []Component) string {
+	if len(components) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\nKnown Components (found by static analysis; use these exact names,\ntypes and paths for components and only add descriptions):\n")
+	for i, c := range components {
+		if i == maxKnownComponents {
+			fmt.Fprintf(&b, "- ... and %d more\n", len(components)-maxKnownComponents)
+			break
+		}
+		fmt.Fprintf(&b, "- %s (%s) at %s\n", c.Name, c.Type, c.Path)
+	}
+	return b.String()
+}
+
+// formatSubprojects lists the subprojects of a monorepo, or returns an empty
+// string for single-project repositories
+func formatSubprojects(subprojects []string) string {
+	if len(subprojects) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\nThis repository is a monorepo with these subprojects; describe the role of\neach in the architecture and how they depend on each other:\n")
+	for _, s := range subprojects {
+		fmt.Fprintf(&b, "- %s\n", s)
+	}
+	return b.String()
+}
+
+// languageInstruction asks for the response in a natural language other than
+// English, or returns an empty string when none is requested
+func languageInstruction(language string) string {
+	if language == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n\nWrite all prose in %s. Keep code, identifiers, file paths, commands and JSON keys unchanged.", language)
+}
+
+// withSuffix appends the user's extra instructions to a prompt
+func withSuffix(prompt, suffix string) string {
+	suffix = strings.TrimSpace(suffix)
+	if suffix == "" {
+		return prompt
+	}
+	return prompt + "\n\nAdditional instructions:\n" + suffix
+}
+
+// isReadme reports whether name is a README file
+func isReadme(name string) bool {
+	base := strings.ToLower(filepath.Base(name))
+	return base == "readme" || strings.HasPrefix(base, "readme.")
+}
+
+// markupFormat returns the documentation markup language of a file, if any
+func markupFormat(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		return "Markdown"
+	case ".rst":
+		return "reStructuredText"
+	case ".adoc", ".asciidoc":
+		return "AsciiDoc"
+	case ".txt":
+		if isReadme(name) {
+			return "plain text"
+		}
+	}
+	return ""
+}
+
+func (c *openAIClient) SummarizeCI(ctx context.Context, input CIInput) (*CIOutput, error) {
+	prompt := withSuffix(fmt.Sprintf(ciPrompt, formatKeyFiles(input.Files))+languageInstruction(input.Language), input.PromptSuffix)
+	response, err := c.makeRequest(ctx, prompt, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &CIOutput{Summary: strings.TrimSpace(response)}, nil
+}
+
+func (c *openAIClient) SummarizeDataModel(ctx context.Context, input DataModelInput) (*DataModelOutput, error) {
+	prompt := withSuffix(fmt.Sprintf(dataModelPrompt, formatKeyFiles(input.Files))+languageInstruction(input.Language), input.PromptSuffix)
+	response, err := c.makeRequest(ctx, prompt, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &DataModelOutput{Summary: strings.TrimSpace(response)}, nil
+}
+
+func (c *openAIClient) IdentifyComponents(ctx context.Context, input AnalyzeInput, found ComponentCallback) ([]Component, error) {
+	format := componentsArrayFormat
+	if found != nil {
+		format = componentsLinesFormat
+	}
+	prompt := fmt.Sprintf(componentsPrompt, input.DirStructure, formatLanguages(input.Languages), formatKeyFiles(input.Files), formatKnownComponents(input.KnownComponents), format)
+	if found != nil {
+		return c.streamComponents(ctx, prompt, found)
+	}
+
+	response, err := c.makeRequest(ctx, prompt, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseComponents(response)
+}
+
+// streamComponents streams the response to a components prompt asking for one
+// object per line, passing each component to found as its line completes. A
+// response that ignores the line format is parsed as an array once complete.
+func (c *openAIClient) streamComponents(ctx context.Context, prompt string, found ComponentCallback) ([]Component, error) {
+	var components []Component
+	stream, flush := lineStream(func(line string) {
+		line = strings.TrimSuffix(strings.TrimSpace(line), ",")
+		if !strings.HasPrefix(line, "{") {
+			return // code fences, array brackets and prose
+		}
+		var component Component
+		if err := json.Unmarshal([]byte(line), &component); err != nil {
+			slog.Debug("skipping unparsable component line", "line", line, "error", err)
+			return
+		}
+		components = append(components, component)
+		found(component)
+	})
+
+	response, err := c.complete(ctx, []chatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: prompt},
+	}, nil, stream)
+	if err != nil {
+		return nil, err
+	}
+	flush()
+	if len(components) > 0 {
+		return components, nil
+	}
+
+	slog.Debug("no component lines in the response, parsing it as an array")
+	components, err = parseComponents(response)
+	if err != nil {
+		return nil, err
+	}
+	for _, component := range components {
+		found(component)
+	}
+	return components, nil
+}
+
+// parseComponents reads the JSON array of components in a response
+func parseComponents(response string) ([]Component, error) {
+	array, ok := extractJSON(response, '[', ']')
+	if !ok {
+		return nil, fmt.Errorf("failed to parse components response: no JSON array found")
+	}
+
+	var components []Component
+	if err := json.Unmarshal([]byte(array), &components); err != nil {
+		return nil, fmt.Errorf("failed to parse components response: %w", err)
+	}
+
+	return components, nil
+}
+
+func (c *openAIClient) Changelog(ctx context.Context, input ChangelogInput) (*ChangelogOutput, error) {
+	var commits strings.Builder
+	for _, commit := range input.Commits {
+		hash := commit.Hash
+		if len(hash) > 7 {
+			hash = hash[:7]
+		}
+		fmt.Fprintf(&commits, "Commit %s: %s\n", hash, commit.Subject)
+		if commit.Body != "" {
+			fmt.Fprintf(&commits, "%s\n", commit.Body)
+		}
+		if commit.Diff != "" {
+			fmt.Fprintf(&commits, "Diff:\n%s\n", commit.Diff)
+		}
+		commits.WriteString("\n---\n\n")
+	}
+
+	prompt := fmt.Sprintf(changelogPrompt, commits.String())
+	response, err := c.makeRequest(ctx, prompt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChangelogOutput{
+		Changelog: response,
+	}, nil
+}
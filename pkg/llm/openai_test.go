@@ -0,0 +1,259 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMakeRequestSuccess(t *testing.T) {
+	server := replay(t, "ok")
+	client := newTestClient(t, server)
+
+	got, err := client.makeRequest(context.Background(), "Describe this repository.", nil)
+	if err != nil {
+		t.Fatalf("makeRequest: %v", err)
+	}
+	if !*record && got != "This repository is a CLI." {
+		t.Errorf("makeRequest = %q, want the recorded content", got)
+	}
+	if n := len(server.Requests()); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+}
+
+func TestMakeRequestErrors(t *testing.T) {
+	tests := []struct {
+		cassette string
+		want     string // Substring of the error
+		is       error  // Sentinel the error must match, if any
+	}{
+		{cassette: "rate_limited", want: "status 429", is: ErrRateLimited},
+		{cassette: "server_error", want: "status 500"},
+		{cassette: "malformed_json", want: "failed to decode response"},
+		{cassette: "empty_choices", want: "returned no choices"},
+		// Error objects next to empty choices match the same sentinels
+		{cassette: "empty_choices_rate_limited", want: "rate limited", is: ErrRateLimited},
+		{cassette: "empty_choices_context_length", want: "context length", is: ErrContextLengthExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cassette, func(t *testing.T) {
+			server := replay(t, tt.cassette)
+			client := newTestClient(t, server)
+
+			_, err := client.makeRequest(context.Background(), "Describe this repository.", nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("makeRequest error = %v, want one containing %q", err, tt.want)
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("makeRequest error = %v, want errors.Is %v", err, tt.is)
+			}
+			// Failed requests are returned to the caller, not retried
+			if n := len(server.Requests()); n != 1 {
+				t.Errorf("sent %d requests, want 1", n)
+			}
+		})
+	}
+}
+
+func TestResponseFormatFallback(t *testing.T) {
+	tests := []struct {
+		cassette    string
+		wantErr     error // Sentinel the first request fails with, if any
+		unsupported bool  // Whether the format stays disabled for later requests
+		withFormat  []bool
+	}{
+		// The format is dropped for the retry and every request after it
+		{cassette: "schema_unsupported", unsupported: true, withFormat: []bool{true, false, false}},
+		// An unrelated bad request is retried without the format, once
+		{cassette: "bad_request_with_schema", withFormat: []bool{true, false, true}},
+		// An oversized prompt fails without touching the format
+		{cassette: "context_length_with_schema", wantErr: ErrContextLengthExceeded, withFormat: []bool{true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cassette, func(t *testing.T) {
+			server := replay(t, tt.cassette)
+			client := newTestClient(t, server)
+
+			_, err := client.makeRequest(context.Background(), "Analyze this repository.", analysisFormat)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("makeRequest error = %v, want errors.Is %v", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("makeRequest: %v", err)
+				}
+				if _, err := client.makeRequest(context.Background(), "Analyze this repository.", analysisFormat); err != nil {
+					t.Fatalf("second makeRequest: %v", err)
+				}
+			}
+
+			if got := client.schemaUnsupported.Load(); got != tt.unsupported {
+				t.Errorf("schemaUnsupported = %v, want %v", got, tt.unsupported)
+			}
+			requests := server.Requests()
+			if len(requests) != len(tt.withFormat) {
+				t.Fatalf("sent %d requests, want %d", len(requests), len(tt.withFormat))
+			}
+			for i, body := range requests {
+				var req chatRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					t.Fatal(err)
+				}
+				if got := req.ResponseFormat != nil; got != tt.withFormat[i] {
+					t.Errorf("request %d has response_format = %v, want %v", i+1, got, tt.withFormat[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSplitLongContent(t *testing.T) {
+	const (
+		header    = "File: a.go\n\n"
+		continued = "File: a.go (continued)\n\n"
+		maxSize   = 34 // Leaves 10 bytes for content after the continued header
+	)
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "empty content",
+			content: "",
+			want:    nil,
+		},
+		{
+			name:    "content filling one chunk",
+			content: "0123456789",
+			want:    []string{header + "0123456789"},
+		},
+		{
+			name:    "content exactly maxSize long",
+			content: strings.Repeat("x", maxSize),
+			want: []string{
+				header + strings.Repeat("x", 10),
+				continued + strings.Repeat("x", 10),
+				continued + strings.Repeat("x", 10),
+				continued + "xxxx",
+			},
+		},
+		{
+			name:    "single line longer than maxSize",
+			content: "abcdefghijklmnopqrstuvwxyz0123456789",
+			want: []string{
+				header + "abcdefghij",
+				continued + "klmnopqrst",
+				continued + "uvwxyz0123",
+				continued + "456789",
+			},
+		},
+		{
+			name:    "split at line boundaries",
+			content: "aaaa\nbbbb\ncccc",
+			want: []string{
+				header + "aaaa\nbbbb",
+				continued + "cccc",
+			},
+		},
+		{
+			name:    "long line without breaking UTF-8",
+			content: strings.Repeat("é", 7),
+			want: []string{
+				header + strings.Repeat("é", 5),
+				continued + "éé",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitLongContent("a.go", tt.content, maxSize)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("splitLongContent() = %q, want %q", got, tt.want)
+			}
+			for i, chunk := range got {
+				if len(chunk) > maxSize {
+					t.Errorf("chunk %d is %d bytes, more than maxSize %d", i, len(chunk), maxSize)
+				}
+				if i > 0 && !strings.HasPrefix(chunk, continued) {
+					t.Errorf("chunk %d = %q, want the %q header", i, chunk, continued)
+				}
+			}
+		})
+	}
+}
+
+// mapCheckpoint is an in-memory Checkpoint
+type mapCheckpoint map[string]string
+
+func (c mapCheckpoint) Load(key string) (string, bool) {
+	response, ok := c[key]
+	return response, ok
+}
+
+func (c mapCheckpoint) Save(key string, response string) error {
+	c[key] = response
+	return nil
+}
+
+func TestCheckpointKeyedByInstructions(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		requests.Add(1)
+		io.WriteString(w, `{"choices":[{"message":{"content":"{\"description\":\"A test repository.\"}"},"finish_reason":"stop"}]}`)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := newOpenAIClient(Config{OpenAIKey: "sk-test", APIBase: server.URL, Model: "gpt-4o-mini"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint := mapCheckpoint{}
+	input := AnalyzeInput{
+		Files:       map[string]string{"main.go": "package main\n", "util.go": "package main\n"},
+		ContextSize: 4000,
+		IsDetailed:  true,
+		Checkpoint:  checkpoint,
+	}
+	analyze := func(language, suffix string) int {
+		t.Helper()
+		input.Language, input.PromptSuffix = language, suffix
+		before := requests.Load()
+		if _, err := client.Analyze(context.Background(), input, nil); err != nil {
+			t.Fatalf("Analyze: %v", err)
+		}
+		return int(requests.Load() - before)
+	}
+
+	first := analyze("", "")
+	if first < 2 {
+		t.Fatalf("first run sent %d requests, want chunks and a summary", first)
+	}
+	tests := []struct {
+		name             string
+		language, suffix string
+		want             int
+	}{
+		// Only the summary is requested again
+		{"same instructions", "", "", 1},
+		{"other language", "Spanish", "", first},
+		{"other prompt suffix", "Spanish", "Focus on security", first},
+	}
+	for _, tt := range tests {
+		if got := analyze(tt.language, tt.suffix); got != tt.want {
+			t.Errorf("%s: sent %d requests, want %d", tt.name, got, tt.want)
+		}
+	}
+}
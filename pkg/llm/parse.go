@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+)
+
+// structuredAnalysis is the JSON shape requested from the model for analyses
+type structuredAnalysis struct {
+	Description  string      `json:"description"`
+	Architecture string      `json:"architecture"`
+	Components   []Component `json:"components"`
+	Setup        string      `json:"setup"`
+	FlowDiagram  string      `json:"flow_diagram"`
+}
+
+// analysisSchema is the JSON schema of structuredAnalysis, sent to endpoints that
+// support response_format so compliant models return valid JSON directly
+const analysisSchema = `{
+  "type": "object",
+  "properties": {
+    "description": {"type": "string"},
+    "architecture": {"type": "string"},
+    "components": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string"},
+          "type": {"type": "string"},
+          "description": {"type": "string"},
+          "path": {"type": "string"}
+        },
+        "required": ["name", "type", "description", "path"],
+        "additionalProperties": false
+      }
+    },
+    "setup": {"type": "string"},
+    "flow_diagram": {"type": "string"}
+  },
+  "required": ["description", "architecture", "components", "setup", "flow_diagram"],
+  "additionalProperties": false
+}`
+
+// parseAnalysis decodes a structured analysis response. Models often wrap JSON in
+// code fences or surround it with prose, so the first balanced object is used;
+// if none can be decoded the whole response becomes the description.
+func parseAnalysis(response string) *AnalyzeOutput {
+	var parsed structuredAnalysis
+	if object, ok := extractJSON(response, '{', '}'); ok {
+		if err := json.Unmarshal([]byte(object), &parsed); err == nil && parsed.Description != "" {
+			return &AnalyzeOutput{
+				Description:  parsed.Description,
+				Architecture: parsed.Architecture,
+				Components:   parsed.Components,
+				Setup:        parsed.Setup,
+				FlowDiagram:  stripCodeFence(parsed.FlowDiagram),
+			}
+		}
+	}
+
+	slog.Warn("model did not return structured JSON; using the raw response as the description")
+	return &AnalyzeOutput{
+		Description: strings.TrimSpace(response),
+	}
+}
+
+// extractJSON returns the first valid JSON value delimited by open and close,
+// looking inside a code fence first and then at the raw response
+func extractJSON(response string, open, close byte) (string, bool) {
+	for _, candidate := range []string{stripCodeFence(response), response} {
+		if value, ok := scanBalanced(candidate, open, close); ok && json.Valid([]byte(value)) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// scanBalanced returns the first balanced span delimited by open and close,
+// ignoring delimiters that appear inside JSON strings
+func scanBalanced(response string, open, close byte) (string, bool) {
+	start := strings.IndexByte(response, open)
+	if start == -1 {
+		return "", false
+	}
+
+	depth := 0
+	inString := false
+	escaped := false
+	for i := start; i < len(response); i++ {
+		ch := response[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && ch == '\\':
+			escaped = true
+		case ch == '"':
+			inString = !inString
+		case inString:
+		case ch == open:
+			depth++
+		case ch == close:
+			depth--
+			if depth == 0 {
+				return response[start : i+1], true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// stripCodeFence returns the contents of the first Markdown code fence in s,
+// or s unchanged when it has no fence
+func stripCodeFence(s string) string {
+	start := strings.Index(s, "```")
+	if start == -1 {
+		return s
+	}
+	body := s[start+3:]
+
+	// Skip the info string, e.g. "json" or "mermaid"
+	if nl := strings.IndexByte(body, '\n'); nl != -1 {
+		body = body[nl+1:]
+	}
+
+	if end := strings.Index(body, "```"); end != -1 {
+		body = body[:end]
+	}
+	return strings.TrimSpace(body)
+}
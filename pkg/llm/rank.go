@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"path"
+	"strings"
+)
+
+// Ranks of files in a detailed analysis, read highest first: entry points,
+// files in conventional source directories, everything else, tests, and last
+// generated and vendored code, which says little about the project's design
+const (
+	rankGenerated = iota
+	rankTest
+	rankOther
+	rankSource
+	rankEntry
+)
+
+// sourceDirs hold a project's own code by convention
+var sourceDirs = map[string]bool{"cmd": true, "src": true, "internal": true, "pkg": true, "lib": true}
+
+// vendorDirs hold copies of third-party code
+var vendorDirs = map[string]bool{"vendor": true, "third_party": true, "node_modules": true, "bower_components": true}
+
+// testDirs hold tests and their fixtures
+var testDirs = map[string]bool{"test": true, "tests": true, "__tests__": true, "testdata": true, "spec": true}
+
+// generatedSuffixes mark files written by code generators or minifiers
+var generatedSuffixes = []string{".pb.go", ".pb.gw.go", "_generated.go", ".gen.go", ".min.js", ".min.css", "_pb2.py"}
+
+// fileRank ranks a file for a detailed analysis by its path and, for
+// generated code, the header generators write
+func fileRank(name, content string) int {
+	base := path.Base(name)
+	dirs := strings.Split(path.Dir(name), "/")
+	switch {
+	case inAny(dirs, vendorDirs) || isGenerated(base, content):
+		return rankGenerated
+	case inAny(dirs, testDirs) || isTestFile(base):
+		return rankTest
+	case strings.HasPrefix(base, "main.") || strings.HasPrefix(base, "index."):
+		return rankEntry
+	case inAny(dirs, sourceDirs):
+		return rankSource
+	}
+	return rankOther
+}
+
+// inAny reports whether any of the directories is in set
+func inAny(dirs []string, set map[string]bool) bool {
+	for _, dir := range dirs {
+		if set[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// isTestFile reports whether a file name follows a test naming convention,
+// e.g. foo_test.go, foo.test.ts, foo.spec.js, foo_spec.rb or test_foo.py
+func isTestFile(base string) bool {
+	stem := strings.TrimSuffix(base, path.Ext(base))
+	return strings.HasSuffix(stem, "_test") || strings.HasSuffix(stem, ".test") ||
+		strings.HasSuffix(stem, ".spec") || strings.HasSuffix(stem, "_spec") ||
+		strings.HasPrefix(stem, "test_")
+}
+
+// isGenerated reports whether a file is generated, by a suffix such as .pb.go
+// or a "Code generated ... DO NOT EDIT." or "@generated" header near the top
+func isGenerated(base, content string) bool {
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return true
+		}
+	}
+	head := content
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	return (strings.Contains(head, "Code generated") && strings.Contains(head, "DO NOT EDIT")) ||
+		strings.Contains(head, "@generated")
+}
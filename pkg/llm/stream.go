@@ -0,0 +1,110 @@
+package llm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// chatStreamChunk is one server-sent event of a streamed chat completion
+type chatStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Error json.RawMessage `json:"error"`
+	Usage *chatUsage      `json:"usage"`
+}
+
+// readStream reads a streamed chat completion into response, passing each
+// piece of content to stream as it arrives. Endpoints that ignore the stream
+// field answer with a plain JSON response, which is passed to stream whole.
+func readStream(resp *http.Response, response *chatResponse, stream StreamCallback) error {
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+			return err
+		}
+		if len(response.Choices) > 0 && response.Choices[0].Message.Content != "" {
+			stream(response.Choices[0].Message.Content)
+		}
+		return nil
+	}
+
+	var content strings.Builder
+	var choice chatChoice
+	received := false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue // comments, event names and the blank lines between events
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("invalid stream event: %w", err)
+		}
+		if len(chunk.Error) > 0 && string(chunk.Error) != "null" {
+			response.Error = chunk.Error
+			return nil
+		}
+		if chunk.Usage != nil {
+			response.Usage = chunk.Usage
+		}
+		for _, part := range chunk.Choices {
+			received = true
+			if part.Delta.Content != "" {
+				content.WriteString(part.Delta.Content)
+				stream(part.Delta.Content)
+			}
+			if part.FinishReason != "" {
+				choice.FinishReason = part.FinishReason
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if received {
+		choice.Message.Content = content.String()
+		response.Choices = []chatChoice{choice}
+	}
+	return nil
+}
+
+// lineStream returns a StreamCallback that passes each complete line of the
+// streamed text to line, and a flush function that passes the final line when
+// the text does not end with a newline
+func lineStream(line func(text string)) (StreamCallback, func()) {
+	var pending strings.Builder
+	stream := func(text string) {
+		for {
+			i := strings.IndexByte(text, '\n')
+			if i == -1 {
+				pending.WriteString(text)
+				return
+			}
+			pending.WriteString(text[:i])
+			line(pending.String())
+			pending.Reset()
+			text = text[i+1:]
+		}
+	}
+	flush := func() {
+		if pending.Len() > 0 {
+			line(pending.String())
+			pending.Reset()
+		}
+	}
+	return stream, flush
+}
@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// responseShare is the part of the context left for the model's response when
+// fitting a single-prompt analysis into it
+const responseShare = 4 // one quarter
+
+// promptBudget returns the tokens a single-prompt analysis may use: the
+// context size less a quarter for the response
+func promptBudget(contextSize int) int {
+	if contextSize <= 0 {
+		contextSize = 4000
+	}
+	return contextSize - contextSize/responseShare
+}
+
+// fitKeyFiles trims the key files of a single-prompt analysis until the
+// prompt fits in promptBudget. Files longer than half their usual limit are
+// shortened first, keeping their beginning and end, and then whole files are
+// left out, least important first: the largest files that are neither READMEs
+// nor entry points such as main.go. It returns the trimmed input and a note
+// for each file it changed, e.g. "go.sum (left out)".
+func fitKeyFiles(input AnalyzeInput, prompt func(AnalyzeInput) string) (AnalyzeInput, []string) {
+	budget := promptBudget(input.ContextSize)
+	if EstimateTokens(prompt(input)) <= budget {
+		return input, nil
+	}
+
+	files := make(map[string]string, len(input.Files))
+	for name, content := range input.Files {
+		files[name] = content
+	}
+	input.Files = files
+	notes := make(map[string]string)
+
+	for _, name := range sortedKeys(files) {
+		limit := keyFileLimit(name) / 2
+		if len(files[name]) > limit {
+			files[name] = truncateMiddle(files[name], limit)
+			notes[name] = "shortened"
+		}
+	}
+
+	// Least important last, so files are dropped from the end
+	names := sortedKeys(files)
+	sort.SliceStable(names, func(i, j int) bool {
+		if ri, rj := keyFileRank(names[i]), keyFileRank(names[j]); ri != rj {
+			return ri < rj
+		}
+		return len(input.Files[names[i]]) < len(input.Files[names[j]])
+	})
+	for len(names) > 1 && EstimateTokens(prompt(input)) > budget {
+		last := names[len(names)-1]
+		names = names[:len(names)-1]
+		delete(files, last)
+		notes[last] = "left out"
+	}
+
+	trimmed := make([]string, 0, len(notes))
+	for _, name := range sortedKeys(notes) {
+		trimmed = append(trimmed, fmt.Sprintf("%s (%s)", name, notes[name]))
+	}
+	if len(trimmed) > 0 {
+		slog.Warn(fmt.Sprintf("Trimmed %d key files to fit the context; raise --context to send more", len(trimmed)), "files", trimmed)
+	}
+	return input, trimmed
+}
+
+// keyFileRank orders key files by importance: READMEs, then entry points, then the rest
+func keyFileRank(name string) int {
+	switch {
+	case isReadme(name):
+		return 0
+	case strings.Contains(name, "main.") || strings.Contains(name, "index."):
+		return 1
+	}
+	return 2
+}
+
+// keyFileLimit returns the characters of a key file included in prompts
+func keyFileLimit(name string) int {
+	if isReadme(name) {
+		return maxReadmeFileSize
+	}
+	return maxKeyFileSize
+}
+
+// truncateMiddle shortens content to about limit characters, keeping the first
+// two thirds and the last third at line boundaries, where definitions and the
+// main logic usually are, and noting how many lines were left out between
+func truncateMiddle(content string, limit int) string {
+	if len(content) <= limit {
+		return content
+	}
+	cut := limit * 2 / 3
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	head := content[:cut]
+	if i := strings.LastIndexByte(head, '\n'); i > 0 {
+		head = head[:i+1]
+	}
+	cut = len(content) - limit/3
+	for cut < len(content) && !utf8.RuneStart(content[cut]) {
+		cut++
+	}
+	tail := content[cut:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+	omitted := strings.Count(content[len(head):len(content)-len(tail)], "\n")
+	return fmt.Sprintf("%s\n... (%d lines omitted) ...\n%s", strings.TrimSuffix(head, "\n"), omitted, tail)
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}